@@ -2,39 +2,99 @@ package ai
 
 import (
 	"candycrush/engine"
-	"fmt"
+	"context"
+	"errors"
 )
 
+var ErrNoValidMove = errors.New("no valid move")
+
+// AI plays the move scoring the most right away. The refills are random to the player:
+// the moves are simulated with a random generator drawn from Seed and the state, not with the one of the state.
 type AI struct {
 	InnerEngine *engine.Engine
+	Seed        uint64
+	// moves simulated at the same time, GOMAXPROCS when 0
+	Workers int
 }
 
-func (ai *AI) FindBestMove(state engine.State) engine.Action {
-	validMoves := ai.InnerEngine.FindValidMoves(state)
+// FindBestMove returns the move with the highest immediate score, the first one listed on a tie
+func (ai *AI) FindBestMove(state engine.State) (engine.Action, error) {
+	return ai.ChooseMove(context.Background(), state)
+}
 
-	// display the valid moves in the console
-	for _, move := range validMoves {
-		println(fmt.Sprintf("Valid move: %v -> %v", move.From, move.To))
-	}
+// ChooseMove returns the move of FindBestMove. When the context is done before all the moves are simulated,
+// it returns the best of the simulated ones, or the first valid move before any.
+func (ai *AI) ChooseMove(ctx context.Context, state engine.State) (engine.Action, error) {
+	validMoves := swaps(ai.InnerEngine.FindValidMoves(state))
 
 	if len(validMoves) == 0 {
-		panic("No valid moves")
+		return engine.Action{}, ErrNoValidMove
+	}
+
+	workers := poolSize(ai.Workers, len(validMoves))
+	scores := make([]int, len(validMoves))
+	done := make([]bool, len(validMoves))
+
+	// one buffer per worker for its simulations
+	buffers := make([]engine.State, workers)
+
+	parallelFor(ctx, workers, len(validMoves), func(worker, i int) {
+		simulated := &buffers[worker]
+		simulated.CopyFrom(state)
+		simulated.Rand = ai.random(state)
+
+		scores[i] = -1
+		if ai.InnerEngine.PlayInPlace(simulated, validMoves[i]) == nil {
+			scores[i] = simulated.Score - state.Score
+		}
+		done[i] = true
+	})
+
+	best := 0
+	for i := range validMoves {
+		if done[i] && (!done[best] || scores[i] > scores[best]) {
+			best = i
+		}
 	}
 
-	// return the first move
-	return validMoves[0]
+	return validMoves[best], nil
 }
 
 // ScoreAction Score an action, higher is better.
-// Applies the action and returns the number of cells destroyed.
-// Does refill the board after exploding and falling to avoid any randomness.
+// Applies the action and returns the score of the whole cascade, -1 when the action is not valid.
+// The refills are the ones of ChooseMove, not the ones the action will really get.
 func (ai *AI) ScoreAction(state engine.State, action engine.Action) int {
-	return ai.ApplyActionAndResolve(state, action)
+	simulated := state
+	simulated.Rand = ai.random(state)
+
+	newState, err := ai.ApplyActionAndResolve(simulated, action)
+	if err != nil {
+		return -1
+	}
+
+	return newState.Score - state.Score
+}
+
+// random returns the generator of the refills simulated from the state, which does not tell its real refills
+func (ai *AI) random(state engine.State) engine.Random {
+	return engine.NewRandom(ai.Seed ^ state.Hash())
+}
+
+// swaps keeps one action of each swap, the one to the right or down: a to b and b to a give the same state.
+// The moves are not modified, they may be the ones of a MoveCache.
+func swaps(moves []engine.Action) []engine.Action {
+	unique := make([]engine.Action, 0, len(moves)/2)
+	for _, move := range moves {
+		if move.To.X > move.From.X || move.To.Y > move.From.Y {
+			unique = append(unique, move)
+		}
+	}
+	return unique
 }
 
-func (ai *AI) ApplyActionAndResolve(state engine.State, action engine.Action) int {
-	//newState := ai.InnerEngine.Swap(action)
-	//ai.InnerEngine.ExplodeAndFallUntilStableSync(newState)
-	//return ai.InnerEngine.State.Score
-	return -1
+// ApplyActionAndResolve simulates the swap and the full cascade on a copy of the state:
+// neither the engine state nor its listeners are touched
+func (ai *AI) ApplyActionAndResolve(state engine.State, action engine.Action) (engine.State, error) {
+	newState, _, err := ai.InnerEngine.Play(state, action)
+	return newState, err
 }
@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+	"sort"
+)
+
+const (
+	DefaultBeamWidth = 50
+	DefaultBeamDepth = 10
+)
+
+// Beam plans a sequence of moves: at each depth it plays every valid move of the states kept,
+// skips the states already reached and keeps the Width best ones. The refills follow the random
+// position of the states, so a plan is exact on levels with a fixed seed.
+// The states kept are expanded by a pool of Workers, GOMAXPROCS when 0.
+// The states are rated by the progress of the objectives, or by the Evaluator when set.
+type Beam struct {
+	InnerEngine *engine.Engine
+	Width       int
+	Depth       int
+	Workers     int
+	Evaluator   *Evaluator
+}
+
+type beamEntry struct {
+	state engine.State
+	// moves of the state, or of the one it was played from
+	moves engine.MoveCache
+	plan  []engine.Action
+	value float64
+}
+
+// FindBestMove returns the first move of the plan
+func (b *Beam) FindBestMove(state engine.State) (engine.Action, error) {
+	return b.ChooseMove(context.Background(), state)
+}
+
+func (b *Beam) ChooseMove(ctx context.Context, state engine.State) (engine.Action, error) {
+	plan, err := b.FindPlan(ctx, state)
+	if err != nil {
+		return engine.Action{}, err
+	}
+
+	return plan[0], nil
+}
+
+// FindPlan returns the first winning sequence of moves found, the shortest one, or else the best rated sequence.
+// When the context is done, the best sequence of the depths completed is returned, or the first valid move before any.
+func (b *Beam) FindPlan(ctx context.Context, state engine.State) ([]engine.Action, error) {
+	width, depth := b.Width, b.Depth
+	if width <= 0 {
+		width = DefaultBeamWidth
+	}
+	if depth <= 0 {
+		depth = DefaultBeamDepth
+	}
+
+	beam := []beamEntry{{state: state}}
+	seen := map[uint64]bool{state.Hash(): true}
+	var best *beamEntry
+
+	for d := 0; d < depth && len(beam) > 0; d++ {
+		children := make([][]beamEntry, len(beam))
+
+		complete := parallelFor(ctx, b.Workers, len(beam), func(_, i int) {
+			children[i] = b.expand(state, beam[i])
+		})
+
+		if !complete {
+			break
+		}
+
+		var next []beamEntry
+
+		// in the order of the beam, so that the same duplicates are skipped and the same win is found whatever the pool
+		for _, entries := range children {
+			for _, child := range entries {
+				hash := child.state.Hash()
+				if seen[hash] {
+					continue
+				}
+				seen[hash] = true
+
+				if b.InnerEngine.IsWon(child.state) && b.InnerEngine.EndsOnWin() {
+					return child.plan, nil
+				}
+
+				next = append(next, child)
+			}
+		}
+
+		// stable, so that a tie keeps the order of the moves
+		sort.SliceStable(next, func(i, j int) bool {
+			return next[i].value > next[j].value
+		})
+
+		if len(next) > width {
+			next = next[:width]
+		}
+
+		if len(next) > 0 && (best == nil || next[0].value > best.value) {
+			best = &next[0]
+		}
+
+		beam = next
+	}
+
+	// cut before the first depth, or every child was already reached
+	if best == nil {
+		if move, ok := b.InnerEngine.FindHint(state); ok {
+			return []engine.Action{move}, nil
+		}
+		return nil, ErrNoValidMove
+	}
+
+	return best.plan, nil
+}
+
+// expand plays every valid move of an entry, rating the children from the searched state
+func (b *Beam) expand(start engine.State, entry beamEntry) []beamEntry {
+	if b.InnerEngine.MovesLeft(entry.state) == 0 {
+		return nil
+	}
+
+	var children []beamEntry
+
+	for _, move := range swaps(b.InnerEngine.ValidMoves(&entry.state, &entry.moves)) {
+		child := beamEntry{moves: entry.moves, plan: append(append([]engine.Action{}, entry.plan...), move)}
+		child.state.CopyFrom(entry.state)
+		if err := b.InnerEngine.PlayInPlace(&child.state, move); err != nil {
+			continue
+		}
+
+		child.value = b.value(start, &child)
+		children = append(children, child)
+	}
+
+	return children
+}
+
+func (b *Beam) value(start engine.State, entry *beamEntry) float64 {
+	if b.Evaluator == nil {
+		return progress(b.InnerEngine, start, entry.state)
+	}
+	return b.Evaluator.evaluate(&entry.state, &entry.moves)
+}
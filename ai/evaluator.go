@@ -0,0 +1,170 @@
+package ai
+
+import "candycrush/engine"
+
+// Features describes a position beyond its score
+type Features struct {
+	Score int
+	// Moves is the number of valid moves
+	Moves int
+	// FourMatches and FiveMatches are the valid moves making a line of four, or of five and more
+	FourMatches int
+	FiveMatches int
+	// Specials is the number of special candies on the board
+	Specials int
+	// IngredientDistance is the number of playable cells between the ingredients and their exit
+	IngredientDistance int
+	Jelly              int
+	BlockerHP          int
+	// Clustering is the number of neighbor candies of the same color, which tend to make matches after a refill
+	Clustering int
+}
+
+// Weights multiply the features of a position, the costs such as the jelly left should weigh negatively
+type Weights struct {
+	Score              float64
+	Moves              float64
+	FourMatches        float64
+	FiveMatches        float64
+	Specials           float64
+	IngredientDistance float64
+	Jelly              float64
+	BlockerHP          float64
+	Clustering         float64
+}
+
+var DefaultWeights = Weights{
+	Score:              1,
+	Moves:              0.1,
+	FourMatches:        1,
+	FiveMatches:        3,
+	Specials:           2,
+	IngredientDistance: -10,
+	Jelly:              -4,
+	BlockerHP:          -3,
+	Clustering:         0.1,
+}
+
+// Evaluator rates a position as the weighted sum of its features, for the search players to rate
+// the states they stop at with more than State.Score
+type Evaluator struct {
+	InnerEngine *engine.Engine
+	Weights     Weights
+}
+
+// NewEvaluator returns an evaluator with the DefaultWeights, the one of the registered search players
+func NewEvaluator(e *engine.Engine) *Evaluator {
+	return &Evaluator{InnerEngine: e, Weights: DefaultWeights}
+}
+
+func (ev *Evaluator) Evaluate(state engine.State) float64 {
+	return ev.evaluate(&state, &engine.MoveCache{})
+}
+
+// evaluate rates a state whose moves the cache may already hold, as ValidMoves finds them
+func (ev *Evaluator) evaluate(state *engine.State, cache *engine.MoveCache) float64 {
+	f := ev.features(state, cache)
+	w := ev.Weights
+
+	return w.Score*float64(f.Score) +
+		w.Moves*float64(f.Moves) +
+		w.FourMatches*float64(f.FourMatches) +
+		w.FiveMatches*float64(f.FiveMatches) +
+		w.Specials*float64(f.Specials) +
+		w.IngredientDistance*float64(f.IngredientDistance) +
+		w.Jelly*float64(f.Jelly) +
+		w.BlockerHP*float64(f.BlockerHP) +
+		w.Clustering*float64(f.Clustering)
+}
+
+func (ev *Evaluator) Features(state engine.State) Features {
+	return ev.features(&state, &engine.MoveCache{})
+}
+
+func (ev *Evaluator) features(state *engine.State, cache *engine.MoveCache) Features {
+	f := Features{Score: state.Score, Jelly: state.Board.JellyLeft()}
+
+	for _, move := range ev.InnerEngine.ValidMoves(state, cache) {
+		f.Moves++
+
+		switch length := matchLength(state, move); {
+		case length >= 5:
+			f.FiveMatches++
+		case length == 4:
+			f.FourMatches++
+		}
+	}
+
+	for y := 0; y < state.Height(); y++ {
+		for x := 0; x < state.Width(); x++ {
+			c := engine.Coord{X: x, Y: y}
+			cell := state.GetCell(c)
+
+			if cell.Special() != engine.NoSpecial {
+				f.Specials++
+			}
+
+			switch {
+			case cell.Color() == engine.Blocker:
+				f.BlockerHP += cell.HP()
+			case cell.Color() == engine.Ingredient:
+				f.IngredientDistance += exitDistance(state, c)
+			case cell.IsCandy():
+				for _, next := range []engine.Coord{{X: x + 1, Y: y}, {X: x, Y: y + 1}} {
+					if state.IsInside(next) && state.GetCell(next).Color() == cell.Color() {
+						f.Clustering++
+					}
+				}
+			}
+		}
+	}
+
+	return f
+}
+
+// matchLength returns the longest line of one color through the swapped cells once the move is played
+func matchLength(state *engine.State, move engine.Action) int {
+	cellAt := func(c engine.Coord) engine.Cell {
+		switch c {
+		case move.From:
+			return state.GetCell(move.To)
+		case move.To:
+			return state.GetCell(move.From)
+		}
+		return state.GetCell(c)
+	}
+
+	longest := 0
+
+	for _, c := range []engine.Coord{move.From, move.To} {
+		color := cellAt(c).Color()
+		if !cellAt(c).IsCandy() {
+			continue
+		}
+
+		for _, step := range []engine.Coord{{X: 1}, {Y: 1}} {
+			length := 1
+			for _, sign := range []int{-1, 1} {
+				next := engine.Coord{X: c.X + sign*step.X, Y: c.Y + sign*step.Y}
+				for state.IsInside(next) && cellAt(next).IsCandy() && cellAt(next).Color() == color {
+					length++
+					next = engine.Coord{X: next.X + sign*step.X, Y: next.Y + sign*step.Y}
+				}
+			}
+			longest = max(longest, length)
+		}
+	}
+
+	return longest
+}
+
+// exitDistance returns the number of playable cells an ingredient still has to fall through
+func exitDistance(state *engine.State, coord engine.Coord) int {
+	distance := 0
+	for y := coord.Y + 1; y < state.Height(); y++ {
+		if state.GetCell(engine.Coord{X: coord.X, Y: y}) != engine.Void {
+			distance++
+		}
+	}
+	return distance
+}
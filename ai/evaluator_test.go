@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"testing"
+)
+
+// the three valid swaps are the G of the top row with either neighbor, lining up three R,
+// and with the R below it, lining up five
+const evaluatorBoard = `
+R R G R R
+I Y R Y B
+G B X2 B G+
+`
+
+func parseTestBoard(t *testing.T, text string) engine.State {
+	t.Helper()
+
+	state, err := engine.ParseBoard(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestEvaluatorFeatures(t *testing.T) {
+	e := &engine.Engine{}
+	state := parseTestBoard(t, evaluatorBoard)
+	state.Board.SetJelly(engine.Coord{X: 0, Y: 0}, 1)
+	state.Board.SetJelly(engine.Coord{X: 4, Y: 0}, 2)
+	state.Score = 7
+
+	expected := Features{
+		Score: 7,
+		// every swap is listed from both of its cells
+		Moves:       6,
+		FiveMatches: 2,
+		Specials:    1,
+		// the G below the ingredient
+		IngredientDistance: 1,
+		Jelly:              3,
+		BlockerHP:          2,
+		// the two pairs of R of the top row
+		Clustering: 2,
+	}
+
+	evaluator := NewEvaluator(e)
+	if features := evaluator.Features(state); features != expected {
+		t.Errorf("features\n%+v\nexpected\n%+v", features, expected)
+	}
+
+	evaluator.Weights = Weights{Score: 1, Moves: 1, Jelly: -2}
+	if value := evaluator.Evaluate(state); value != 7+6-6 {
+		t.Errorf("evaluated to %v, expected 7", value)
+	}
+}
+
+func TestMatchLength(t *testing.T) {
+	state := parseTestBoard(t, "R R G R Y\nB Y R Y B")
+
+	for _, test := range []struct {
+		move     engine.Action
+		expected int
+	}{
+		{engine.Action{From: engine.Coord{X: 2, Y: 1}, To: engine.Coord{X: 2, Y: 0}}, 4},
+		{engine.Action{From: engine.Coord{X: 2, Y: 0}, To: engine.Coord{X: 3, Y: 0}}, 3},
+		{engine.Action{From: engine.Coord{X: 0, Y: 0}, To: engine.Coord{X: 0, Y: 1}}, 1},
+	} {
+		if length := matchLength(&state, test.move); length != test.expected {
+			t.Errorf("%v lines up %d candies, expected %d", test.move, length, test.expected)
+		}
+	}
+}
+
+func TestRegisteredSearchPlayersEvaluate(t *testing.T) {
+	e := &engine.Engine{}
+
+	for _, name := range []string{"expectimax", "mcts", "beam"} {
+		player, err := NewPlayer(name, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var evaluator *Evaluator
+		switch player := player.(type) {
+		case *Expectimax:
+			evaluator = player.Evaluator
+		case *MCTS:
+			evaluator = player.Evaluator
+		case *Beam:
+			evaluator = player.Evaluator
+		}
+
+		if evaluator == nil || evaluator.Weights != DefaultWeights {
+			t.Errorf("%s does not evaluate with the default weights", name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+	"math"
+)
+
+const (
+	DefaultExpectimaxDepth   = 2
+	DefaultExpectimaxSamples = 4
+)
+
+// Expectimax looks up to Depth moves ahead. The refills are random to the player: at each chance node
+// the cascade is resolved Samples times with random generators drawn from Seed and the state, and the scores are averaged.
+// The search deepens one move at a time, so a deadline cuts it with the best move of the deepest search completed.
+// The moves of the searched state are evaluated by a pool of Workers, GOMAXPROCS when 0.
+// The states reached are rated by their score, or by the Evaluator when set.
+type Expectimax struct {
+	InnerEngine *engine.Engine
+	Depth       int
+	Samples     int
+	Seed        uint64
+	Workers     int
+	Evaluator   *Evaluator
+}
+
+// FindBestMove returns the move with the best expected score over the next Depth moves, the first one listed on a tie
+func (x *Expectimax) FindBestMove(state engine.State) (engine.Action, error) {
+	return x.ChooseMove(context.Background(), state)
+}
+
+// ChooseMove searches until Depth or until the context is done, it then returns the best move found so far,
+// the first valid move when the context is done before any
+func (x *Expectimax) ChooseMove(ctx context.Context, state engine.State) (engine.Action, error) {
+	var cache engine.MoveCache
+	validMoves := swaps(x.InnerEngine.ValidMoves(&state, &cache))
+	if len(validMoves) == 0 {
+		return engine.Action{}, ErrNoValidMove
+	}
+
+	best := 0
+
+	for depth := 1; depth <= x.depth(); depth++ {
+		values, done := x.evaluate(ctx, state, cache, validMoves, depth)
+		cut := ctx.Err() != nil
+
+		// a cut search is only worth its best completed move on the first depth, when nothing better is known
+		if cut && depth > 1 {
+			break
+		}
+
+		found := false
+		for i := range values {
+			if done[i] && (!found || values[i] > values[best]) {
+				best = i
+				found = true
+			}
+		}
+
+		if cut {
+			break
+		}
+	}
+
+	return validMoves[best], nil
+}
+
+// evaluate returns the expected value of every move up to depth, done tells the ones evaluated before the context was done
+func (x *Expectimax) evaluate(ctx context.Context, state engine.State, cache engine.MoveCache, validMoves []engine.Action, depth int) ([]float64, []bool) {
+	workers := poolSize(x.Workers, len(validMoves))
+	values := make([]float64, len(validMoves))
+	done := make([]bool, len(validMoves))
+
+	// each worker has one buffer per depth for the simulated states, the samples are resolved one after the other
+	buffers := make([][]engine.State, workers)
+	for w := range buffers {
+		buffers[w] = make([]engine.State, depth)
+	}
+
+	parallelFor(ctx, workers, len(validMoves), func(worker, i int) {
+		values[i] = x.chance(ctx, state, cache, validMoves[i], depth, buffers[worker])
+		done[i] = ctx.Err() == nil
+	})
+
+	return values, done
+}
+
+// chance returns the expected value of a move followed by the best moves up to depth.
+// The cache holds the moves of the state, each sample lists its own from a copy of it.
+func (x *Expectimax) chance(ctx context.Context, state engine.State, cache engine.MoveCache, move engine.Action, depth int, buffers []engine.State) float64 {
+	samples := x.samples()
+	random := engine.NewRandom(x.Seed ^ state.Hash())
+	total := 0.0
+
+	for sample := 0; sample < samples; sample++ {
+		next := &buffers[depth-1]
+		next.CopyFrom(state)
+		next.Rand = engine.NewRandom(random.Uint64())
+
+		if err := x.InnerEngine.PlayInPlace(next, move); err != nil {
+			return math.Inf(-1)
+		}
+
+		total += x.max(ctx, *next, cache, depth-1, buffers)
+	}
+
+	return total / float64(samples)
+}
+
+// max returns the best expected value of the state up to depth, the value of the state itself when the game is over
+func (x *Expectimax) max(ctx context.Context, state engine.State, cache engine.MoveCache, depth int, buffers []engine.State) float64 {
+	if depth == 0 || x.InnerEngine.MovesLeft(state) == 0 || (x.InnerEngine.IsWon(state) && x.InnerEngine.EndsOnWin()) {
+		return x.value(&state, &cache)
+	}
+
+	validMoves := swaps(x.InnerEngine.ValidMoves(&state, &cache))
+	if len(validMoves) == 0 {
+		return x.value(&state, &cache)
+	}
+
+	best := math.Inf(-1)
+
+	for _, move := range validMoves {
+		// the value is thrown away anyway
+		if ctx.Err() != nil {
+			return best
+		}
+
+		best = max(best, x.chance(ctx, state, cache, move, depth, buffers))
+	}
+
+	return best
+}
+
+func (x *Expectimax) value(state *engine.State, cache *engine.MoveCache) float64 {
+	if x.Evaluator == nil {
+		return float64(state.Score)
+	}
+	return x.Evaluator.evaluate(state, cache)
+}
+
+func (x *Expectimax) depth() int {
+	if x.Depth <= 0 {
+		return DefaultExpectimaxDepth
+	}
+	return x.Depth
+}
+
+func (x *Expectimax) samples() int {
+	if x.Samples <= 0 {
+		return DefaultExpectimaxSamples
+	}
+	return x.Samples
+}
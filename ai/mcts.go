@@ -0,0 +1,303 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	DefaultMCTSIterations   = 1000
+	DefaultMCTSRolloutDepth = 10
+	DefaultMCTSRollouts     = 4
+)
+
+// the evaluation gained by a rollout for a reward of 0.75
+const evaluationScale = 50
+
+// MCTS searches the moves with Monte Carlo tree search: UCT selection, then rollouts over the engine rules
+// until the game is over or RolloutDepth moves were played. A rollout is rewarded for the progress made
+// towards the objectives since the searched state, or for the score gained when the rules have none.
+// With an Evaluator, the evaluation gained is averaged in.
+// The refills are random to the player, so the tree is open loop: a node is a sequence of moves, played again at each
+// iteration with refills drawn from Seed, and its statistics average over their outcomes. Once a move is played,
+// its subtree is kept for the next search.
+// Each expanded node is rated by Rollouts rollouts, played by a pool of Workers, GOMAXPROCS when 0: the random moves
+// of a rollout are drawn from Seed, the iteration and the index of the rollout, so the search does not depend on the pool.
+type MCTS struct {
+	InnerEngine *engine.Engine
+	// the search stops after Iterations, or once TimeBudget has elapsed, whichever comes first
+	Iterations int
+	TimeBudget time.Duration
+	// UCT exploration constant, sqrt(2) when 0
+	Exploration  float64
+	RolloutDepth int
+	// GreedyRollouts plays the best immediate move of a few random ones instead of a random move
+	GreedyRollouts bool
+	Rollouts       int
+	Seed           uint64
+	Workers        int
+	Evaluator      *Evaluator
+
+	root *mctsNode
+	// the state the root was searched from, with its moves
+	rootState engine.State
+	rootMoves engine.MoveCache
+	// progress of the objectives is measured from the state the tree was started from
+	start      engine.State
+	startValue float64
+}
+
+type mctsNode struct {
+	move     engine.Action
+	parent   *mctsNode
+	children []*mctsNode
+	visits   int
+	reward   float64
+}
+
+// FindBestMove returns the most visited move of the search
+func (m *MCTS) FindBestMove(state engine.State) (engine.Action, error) {
+	return m.ChooseMove(context.Background(), state)
+}
+
+// ChooseMove searches until Iterations, TimeBudget or the context is done, it then returns the most visited move so far,
+// the first valid move when the context is done before any rollout
+func (m *MCTS) ChooseMove(ctx context.Context, state engine.State) (engine.Action, error) {
+	if m.TimeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.TimeBudget)
+		defer cancel()
+	}
+
+	var moves engine.MoveCache
+	validMoves := swaps(m.InnerEngine.ValidMoves(&state, &moves))
+	if len(validMoves) == 0 || m.isOver(state) {
+		return engine.Action{}, ErrNoValidMove
+	}
+
+	m.root = m.reuse(state)
+	m.rootState = state
+	m.rootMoves = moves
+
+	iterations := m.Iterations
+	if iterations <= 0 && m.TimeBudget <= 0 {
+		iterations = DefaultMCTSIterations
+	}
+
+	rollouts := m.Rollouts
+	if rollouts <= 0 {
+		rollouts = DefaultMCTSRollouts
+	}
+
+	workers := poolSize(m.Workers, rollouts)
+	buffers := make([]rolloutBuffers, workers)
+	rewards := make([]float64, rollouts)
+
+	// the state the iteration reached, read by all the rollouts
+	var leaf engine.State
+	var leafMoves engine.MoveCache
+
+	for done := 0; ctx.Err() == nil && (iterations <= 0 || done < iterations); done++ {
+		random := engine.NewRandom(m.Seed ^ state.Hash() ^ uint64(m.root.visits)<<32)
+		node := m.selectAndExpand(&leaf, &leafMoves, &random)
+		seed := random.Uint64()
+
+		complete := parallelFor(ctx, workers, rollouts, func(worker, i int) {
+			rewards[i] = m.rollout(&leaf, leafMoves, engine.NewRandom(seed+uint64(i)), &buffers[worker])
+		})
+
+		// the expanded node stays, without statistics
+		if !complete {
+			break
+		}
+
+		reward := 0.0
+		for _, r := range rewards {
+			reward += r
+		}
+
+		for ; node != nil; node = node.parent {
+			node.visits += rollouts
+			node.reward += reward
+		}
+	}
+
+	// the children of a reused root were expanded after other refills, only the valid ones count
+	var best *mctsNode
+	for _, child := range m.root.children {
+		if child.visits > 0 && slices.Contains(validMoves, child.move) && (best == nil || child.visits > best.visits) {
+			best = child
+		}
+	}
+
+	// cut before any rollout
+	if best == nil {
+		return validMoves[0], nil
+	}
+
+	return best.move, nil
+}
+
+// reuse returns the node of the previous tree whose position is the state, with its statistics: the root when the state
+// is the same, or the child of the move played. The move is found by playing the moves of the root again with the real
+// refills, which are no secret anymore. Otherwise the tree starts again from the state.
+func (m *MCTS) reuse(state engine.State) *mctsNode {
+	if m.root != nil {
+		if m.rootState.Equal(state) {
+			return m.root
+		}
+
+		for _, child := range m.root.children {
+			next, _, err := m.InnerEngine.Play(m.rootState, child.move)
+			if err == nil && next.Equal(state) {
+				child.parent = nil
+				return child
+			}
+		}
+	}
+
+	m.start = state
+	if m.Evaluator != nil {
+		m.startValue = m.Evaluator.Evaluate(state)
+	}
+
+	return &mctsNode{}
+}
+
+// selectAndExpand plays the moves of the tree from the root by UCT, on refills drawn from random, until a move of
+// the state reached has no node yet. It adds that node and returns it, with the state and its moves.
+func (m *MCTS) selectAndExpand(state *engine.State, moves *engine.MoveCache, random *engine.Random) *mctsNode {
+	state.CopyFrom(m.rootState)
+	*moves = m.rootMoves
+
+	node := m.root
+
+	for !m.isOver(*state) {
+		validMoves := swaps(m.InnerEngine.ValidMoves(state, moves))
+		if len(validMoves) == 0 {
+			break
+		}
+
+		child, expanded := m.selectChild(node, validMoves)
+
+		state.Rand = engine.NewRandom(random.Uint64())
+		if err := m.InnerEngine.PlayInPlace(state, child.move); err != nil {
+			break
+		}
+
+		node = child
+		if expanded {
+			break
+		}
+	}
+
+	return node
+}
+
+// selectChild returns the node of the first valid move without one, added to the tree, or else the best child by UCT
+// among the ones of the valid moves
+func (m *MCTS) selectChild(node *mctsNode, validMoves []engine.Action) (*mctsNode, bool) {
+	var valid []*mctsNode
+
+	for _, move := range validMoves {
+		i := slices.IndexFunc(node.children, func(child *mctsNode) bool {
+			return child.move == move
+		})
+
+		if i < 0 {
+			child := &mctsNode{move: move, parent: node}
+			node.children = append(node.children, child)
+			return child, true
+		}
+
+		valid = append(valid, node.children[i])
+	}
+
+	exploration := m.Exploration
+	if exploration == 0 {
+		exploration = math.Sqrt2
+	}
+
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	logVisits := math.Log(float64(node.visits))
+
+	for _, child := range valid {
+		value := math.Inf(1)
+		if child.visits > 0 {
+			value = child.reward/float64(child.visits) + exploration*math.Sqrt(logVisits/float64(child.visits))
+		}
+
+		if value > bestValue {
+			best = child
+			bestValue = value
+		}
+	}
+
+	return best, false
+}
+
+// rolloutBuffers are the states a worker simulates its rollouts on, with the moves of the simulated one
+type rolloutBuffers struct {
+	simulated, candidate engine.State
+	moves                engine.MoveCache
+}
+
+// rollout plays moves from the state, whose moves are given, and returns the reward of where it ends
+func (m *MCTS) rollout(state *engine.State, moves engine.MoveCache, random engine.Random, buffers *rolloutBuffers) float64 {
+	simulated, candidate := &buffers.simulated, &buffers.candidate
+	simulated.CopyFrom(*state)
+	simulated.Rand = engine.NewRandom(random.Uint64())
+	buffers.moves = moves
+
+	depth := m.RolloutDepth
+	if depth <= 0 {
+		depth = DefaultMCTSRolloutDepth
+	}
+
+	for i := 0; i < depth && !m.isOver(*simulated); i++ {
+		// every swap is listed both ways, a random move is still a random swap
+		moves := m.InnerEngine.ValidMoves(simulated, &buffers.moves)
+		if len(moves) == 0 {
+			break
+		}
+
+		move := moves[random.Intn(len(moves))]
+
+		if m.GreedyRollouts {
+			bestScore := -1
+			for try := 0; try < 3; try++ {
+				option := moves[random.Intn(len(moves))]
+				candidate.CopyFrom(*simulated)
+				if m.InnerEngine.PlayInPlace(candidate, option) == nil && candidate.Score > bestScore {
+					move = option
+					bestScore = candidate.Score
+				}
+			}
+		}
+
+		if err := m.InnerEngine.PlayInPlace(simulated, move); err != nil {
+			break
+		}
+	}
+
+	return m.reward(simulated, &buffers.moves)
+}
+
+// reward rates where a rollout ends in [0, 1]
+func (m *MCTS) reward(state *engine.State, moves *engine.MoveCache) float64 {
+	reward := progress(m.InnerEngine, m.start, *state)
+	if m.Evaluator == nil {
+		return reward
+	}
+
+	gain := m.Evaluator.evaluate(state, moves) - m.startValue
+	return (reward + 0.5 + 0.5*gain/(math.Abs(gain)+evaluationScale)) / 2
+}
+
+func (m *MCTS) isOver(state engine.State) bool {
+	return m.InnerEngine.MovesLeft(state) == 0 || (m.InnerEngine.IsWon(state) && m.InnerEngine.EndsOnWin())
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+// the search after a move played starts from the subtree of that move
+func TestMCTSReusesTheTreeOfTheMovePlayed(t *testing.T) {
+	for _, level := range []string{"01-first-steps", "02-jelly", "03-blockers"} {
+		e := loadTestLevel(t, level, 1)
+		m := &MCTS{InnerEngine: e, Iterations: 50, Seed: 1, Workers: 1}
+
+		for turn := 0; turn < 6 && !e.IsGameOver(); turn++ {
+			move, err := m.ChooseMove(context.Background(), e.Snapshot())
+			if err != nil {
+				t.Fatalf("%s, turn %d: %v", level, turn, err)
+			}
+
+			if err := e.PlayTurn(move); err != nil {
+				t.Fatalf("%s, turn %d: %v", level, turn, err)
+			}
+
+			if e.IsGameOver() {
+				break
+			}
+
+			if root := m.reuse(e.Snapshot()); root.visits == 0 {
+				t.Errorf("%s, turn %d: the search after %v starts without visits", level, turn, move)
+			}
+		}
+	}
+}
+
+// a position searched again keeps its statistics, another one starts a new tree
+func TestMCTSReuseKeepsTheRoot(t *testing.T) {
+	e := loadTestLevel(t, "02-jelly", 1)
+	m := &MCTS{InnerEngine: e, Iterations: 20, Seed: 1, Workers: 1}
+	state := e.Snapshot()
+
+	if _, err := m.ChooseMove(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+
+	if root := m.reuse(state); root != m.root || root.visits == 0 {
+		t.Error("the same state did not keep the root")
+	}
+
+	other := loadTestLevel(t, "03-blockers", 1).Snapshot()
+	if root := m.reuse(other); root.visits != 0 || len(root.children) != 0 {
+		t.Error("another state reused the tree")
+	}
+}
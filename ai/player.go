@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrUnknownPlayer = errors.New("unknown player")
+
+// Player chooses the next move of a game. The context only cuts its search short:
+// it answers with the best move found so far, a legal one as long as the state has any.
+type Player interface {
+	ChooseMove(ctx context.Context, state engine.State) (engine.Action, error)
+}
+
+// NewPlayerFunc returns a player of a strategy for the games of an engine
+type NewPlayerFunc func(e *engine.Engine) Player
+
+// DefaultPlayer is the strategy used for the hints when none is chosen
+const DefaultPlayer = "greedy"
+
+var registry = map[string]NewPlayerFunc{
+	"random": func(e *engine.Engine) Player {
+		return &Random{InnerEngine: e}
+	},
+	"first": func(e *engine.Engine) Player {
+		return &First{InnerEngine: e}
+	},
+	"greedy": func(e *engine.Engine) Player {
+		return &AI{InnerEngine: e}
+	},
+	// the search players rate the states they stop at with more than the score
+	"expectimax": func(e *engine.Engine) Player {
+		return &Expectimax{InnerEngine: e, Evaluator: NewEvaluator(e)}
+	},
+	"mcts": func(e *engine.Engine) Player {
+		return &MCTS{InnerEngine: e, Evaluator: NewEvaluator(e)}
+	},
+	"beam": func(e *engine.Engine) Player {
+		return &Beam{InnerEngine: e, Evaluator: NewEvaluator(e)}
+	},
+}
+
+// Register adds a strategy to the ones NewPlayer knows, replacing the one with the same name
+func Register(name string, newPlayer NewPlayerFunc) {
+	registry[name] = newPlayer
+}
+
+// NewPlayer returns a player of the strategy registered under the name
+func NewPlayer(name string, e *engine.Engine) (Player, error) {
+	newPlayer, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s, expected one of %v", ErrUnknownPlayer, name, PlayerNames())
+	}
+
+	return newPlayer(e), nil
+}
+
+// PlayerNames returns the names of the registered strategies in alphabetical order
+func PlayerNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// a player answers with a legal move even when its time is up before it starts
+func TestPlayersAnswerAfterTheDeadline(t *testing.T) {
+	e := loadTestLevel(t, "02-jelly", 1)
+	state := e.Snapshot()
+	validMoves := e.FindValidMoves(state)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, name := range PlayerNames() {
+		player, err := NewPlayer(name, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		move, err := player.ChooseMove(ctx, state)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if !slices.Contains(validMoves, move) {
+			t.Errorf("%s: %v is not a valid move", name, move)
+		}
+	}
+}
+
+func TestSwapsListEachSwapOnce(t *testing.T) {
+	e := loadTestLevel(t, "02-jelly", 1)
+	validMoves := e.FindValidMoves(e.Snapshot())
+	unique := swaps(validMoves)
+
+	if len(unique)*2 != len(validMoves) {
+		t.Errorf("%d swaps kept of %d valid moves", len(unique), len(validMoves))
+	}
+
+	for _, move := range validMoves {
+		reverse := engine.Action{From: move.To, To: move.From}
+		if slices.Contains(unique, move) == slices.Contains(unique, reverse) {
+			t.Errorf("%v is not kept exactly one way", move)
+		}
+	}
+}
+
+func TestPlayersStopAtTheDeadline(t *testing.T) {
+	e := loadTestLevel(t, "02-jelly", 1)
+	state := e.Snapshot()
+
+	for name, player := range map[string]Player{
+		"expectimax": &Expectimax{InnerEngine: e, Depth: 8, Samples: 8},
+		"mcts":       &MCTS{InnerEngine: e, Iterations: 1 << 30},
+		"beam":       &Beam{InnerEngine: e, Width: 1 << 20, Depth: 100},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+
+		_, err := player.ChooseMove(ctx, state)
+		cancel()
+
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s answered %v after a 50ms deadline", name, elapsed)
+		}
+	}
+}
+
+// a beam search left without any child still answers while the state has a valid move
+func TestBeamWithoutChildren(t *testing.T) {
+	e := loadTestLevel(t, "02-jelly", 1)
+	state := e.Snapshot()
+	state.Moves = e.Rules.MoveLimit
+
+	plan, err := (&Beam{InnerEngine: e}).FindPlan(context.Background(), state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hint, _ := e.FindHint(state); len(plan) != 1 || plan[0] != hint {
+		t.Errorf("plan %v, expected the hint %v", plan, hint)
+	}
+}
+
+// largeTestLevel returns the engine of a level of the largest size, with a move limit
+func largeTestLevel(b *testing.B) *engine.Engine {
+	size := engine.MaxLevelSize
+	level, err := engine.ParseLevel([]byte(fmt.Sprintf(`{"version": 1, "name": "large", "width": %d, "height": %d, "moves": 30, "seed": 1}`, size, size)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	e, err := level.NewEngine()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return e
+}
+
+// benchmarkPlayer measures the search of a move with an evaluator, on one worker
+func benchmarkPlayer(b *testing.B, e *engine.Engine, newPlayer func(e *engine.Engine) Player) {
+	state := e.Snapshot()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := newPlayer(e).ChooseMove(context.Background(), state); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// the searches stop at the same depth with or without the cache of the valid moves, the large board gets shallower ones
+
+func BenchmarkExpectimax(b *testing.B) {
+	benchmarkPlayer(b, loadTestLevel(b, "02-jelly", 1), func(e *engine.Engine) Player {
+		return &Expectimax{InnerEngine: e, Depth: 2, Samples: 2, Workers: 1, Evaluator: NewEvaluator(e)}
+	})
+}
+
+func BenchmarkExpectimaxLarge(b *testing.B) {
+	benchmarkPlayer(b, largeTestLevel(b), func(e *engine.Engine) Player {
+		return &Expectimax{InnerEngine: e, Depth: 1, Samples: 2, Workers: 1, Evaluator: NewEvaluator(e)}
+	})
+}
+
+func BenchmarkMCTS(b *testing.B) {
+	benchmarkPlayer(b, loadTestLevel(b, "02-jelly", 1), func(e *engine.Engine) Player {
+		return &MCTS{InnerEngine: e, Iterations: 100, Workers: 1, Evaluator: NewEvaluator(e)}
+	})
+}
+
+func BenchmarkMCTSLarge(b *testing.B) {
+	benchmarkPlayer(b, largeTestLevel(b), func(e *engine.Engine) Player {
+		return &MCTS{InnerEngine: e, Iterations: 20, Workers: 1, Evaluator: NewEvaluator(e)}
+	})
+}
+
+func BenchmarkBeam(b *testing.B) {
+	benchmarkPlayer(b, loadTestLevel(b, "02-jelly", 1), func(e *engine.Engine) Player {
+		return &Beam{InnerEngine: e, Width: 10, Depth: 4, Workers: 1, Evaluator: NewEvaluator(e)}
+	})
+}
+
+func BenchmarkBeamLarge(b *testing.B) {
+	benchmarkPlayer(b, largeTestLevel(b), func(e *engine.Engine) Player {
+		return &Beam{InnerEngine: e, Width: 4, Depth: 2, Workers: 1, Evaluator: NewEvaluator(e)}
+	})
+}
@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// poolSize returns the number of workers for n tasks: workers, or GOMAXPROCS when it is 0
+func poolSize(workers, n int) int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return max(min(workers, n), 1)
+}
+
+// parallelFor runs task for every index in [0, n) on a pool of workers and waits for all of them.
+// A task is given the index of its worker, to use the buffers of that worker, and must only write
+// the results of its own index: reading them in index order afterwards does not depend on the scheduling.
+// Once the context is done, the workers stop taking tasks and it returns false.
+func parallelFor(ctx context.Context, workers, n int, task func(worker, i int)) bool {
+	workers = poolSize(workers, n)
+
+	if workers == 1 {
+		for i := 0; i < n; i++ {
+			if ctx.Err() != nil {
+				return false
+			}
+			task(0, i)
+		}
+		return ctx.Err() == nil
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				task(worker, i)
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	return ctx.Err() == nil
+}
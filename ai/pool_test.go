@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func loadTestLevel(t testing.TB, name string, seed uint64) *engine.Engine {
+	t.Helper()
+
+	data, err := os.ReadFile("../levels/packs/classic/" + name + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	level, err := engine.ParseLevel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	level.Seed = seed
+
+	e, err := level.NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+// the players must choose the same move whatever the size of their pool
+func TestParallelPlayersMatchSerial(t *testing.T) {
+	players := map[string]func(e *engine.Engine, workers int) Player{
+		"greedy": func(e *engine.Engine, workers int) Player {
+			return &AI{InnerEngine: e, Seed: 3, Workers: workers}
+		},
+		"expectimax": func(e *engine.Engine, workers int) Player {
+			return &Expectimax{InnerEngine: e, Depth: 2, Samples: 2, Seed: 3, Workers: workers}
+		},
+		"mcts": func(e *engine.Engine, workers int) Player {
+			return &MCTS{InnerEngine: e, Iterations: 50, Seed: 3, Workers: workers}
+		},
+		"beam": func(e *engine.Engine, workers int) Player {
+			return &Beam{InnerEngine: e, Width: 8, Depth: 3, Workers: workers}
+		},
+	}
+
+	// more workers than moves or rollouts as well, and a pool even on a single processor
+	pools := []int{runtime.GOMAXPROCS(0), 4, 64}
+
+	for name, newPlayer := range players {
+		for _, level := range []string{"02-jelly", "04-ingredients"} {
+			for seed := uint64(1); seed <= 2; seed++ {
+				e := loadTestLevel(t, level, seed)
+				state := e.Snapshot()
+
+				serial, err := newPlayer(e, 1).ChooseMove(context.Background(), state)
+				if err != nil {
+					t.Fatalf("%s on %s: %v", name, level, err)
+				}
+
+				for _, workers := range pools {
+					parallel, err := newPlayer(e, workers).ChooseMove(context.Background(), state)
+					if err != nil {
+						t.Fatalf("%s on %s with %d workers: %v", name, level, workers, err)
+					}
+
+					if parallel != serial {
+						t.Errorf("%s on %s, seed %d: %v with %d workers, %v with one", name, level, seed, parallel, workers, serial)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package ai
+
+import "candycrush/engine"
+
+// progress rates a state reached from start in [0, 1]: the progress towards the objectives is below 0.5,
+// a win is above it, more with more moves left. Without objectives, the score gained is rated instead,
+// as well as above a win that does not end the game.
+func progress(e *engine.Engine, start, state engine.State) float64 {
+	objectives := e.Rules.Objectives
+	gained := float64(state.Score - start.Score)
+
+	if len(objectives) == 0 {
+		return gained / (gained + 100)
+	}
+
+	if e.IsWon(state) && !e.EndsOnWin() {
+		return 0.5 + 0.5*gained/(gained+100)
+	}
+
+	if e.IsWon(state) {
+		left := e.MovesLeft(state)
+		if left <= 0 {
+			return 0.5
+		}
+		return 0.5 + 0.5*float64(left)/float64(left+state.Moves-start.Moves)
+	}
+
+	done := 0.0
+	for _, objective := range objectives {
+		remaining := objective.Remaining(start)
+		if remaining == 0 {
+			done++
+			continue
+		}
+		done += float64(remaining-objective.Remaining(state)) / float64(remaining)
+	}
+
+	return 0.5 * done / float64(len(objectives))
+}
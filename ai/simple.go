@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"candycrush/engine"
+	"context"
+)
+
+// First plays the first valid move listed, the one the hints used to show
+type First struct {
+	InnerEngine *engine.Engine
+}
+
+// ChooseMove answers at once, the context does not matter
+func (f *First) ChooseMove(_ context.Context, state engine.State) (engine.Action, error) {
+	move, ok := f.InnerEngine.FindHint(state)
+	if !ok {
+		return engine.Action{}, ErrNoValidMove
+	}
+	return move, nil
+}
+
+// Random plays any valid move, drawn from Seed
+type Random struct {
+	InnerEngine *engine.Engine
+	Seed        uint64
+	random      *engine.Random
+}
+
+// ChooseMove answers at once, the context does not matter
+func (r *Random) ChooseMove(_ context.Context, state engine.State) (engine.Action, error) {
+	moves := r.InnerEngine.FindValidMoves(state)
+	if len(moves) == 0 {
+		return engine.Action{}, ErrNoValidMove
+	}
+
+	if r.random == nil {
+		random := engine.NewRandom(r.Seed)
+		r.random = &random
+	}
+
+	return moves[r.random.Intn(len(moves))], nil
+}
@@ -3,90 +3,289 @@ package controller
 import (
 	"candycrush/ai"
 	"candycrush/engine"
+	"candycrush/levels"
 	"candycrush/ui"
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 )
 
 type Controller struct {
 	engine *engine.Engine
-	ai     *ai.AI
-	ui     *ui.UI
+	// the player shows its move as a hint, and plays it on autoplay
+	player     ai.Player
+	playerName string
+	autoplay   bool
+	moveTime   time.Duration
+	ui         *ui.UI
+	savePath   string
+	// recordPath is where the recorder writes the replay on close
+	recordPath string
+	recorder   *engine.Recorder
+	// the hint is searched in the background, mutex guards its result and the search going on
+	mutex sync.Mutex
+	hint  *engine.Action
+	// search counts the searches started, so that the result of a cancelled one is dropped
+	search int
+	// cancelSearch stops the search going on, searchDone is closed once it returned
+	cancelSearch context.CancelFunc
+	searchDone   chan struct{}
 }
 
-func NewController() *Controller {
-	myEngine := engine.Engine{}
-	myEngine.InitRandom()
+func NewController(options Options) (*Controller, error) {
+	myEngine, err := newEngine(options)
+	if err != nil {
+		return nil, err
+	}
 
-	uiInst := ui.BuildUI(&myEngine.State)
+	playerName := options.Player
+	if playerName == "" {
+		playerName = ai.DefaultPlayer
+	}
 
-	myAI := ai.AI{
-		InnerEngine: &myEngine,
+	if _, err := ai.NewPlayer(playerName, myEngine); err != nil {
+		return nil, err
 	}
 
 	cont := &Controller{
-		engine: &myEngine,
-		ai:     &myAI,
-		ui:     uiInst,
-	}
-
-	myEngine.HandleChangedAfterExplode = func(changed bool, exploded [][]bool) {
-		if changed {
-			uiInst.SetAnimStep(ui.Explode)
-			uiInst.SetAnimStart()
-			uiInst.Destroyed = exploded
-
-			println(fmt.Sprintf("Setting destroying to true, animationSince: %s", uiInst.AnimationSince))
-		} else {
-			println("Explode and fall until stable finished")
-			uiInst.SetAnimStep(ui.Idle)
-			cont.showAIMoves()
+		playerName: playerName,
+		autoplay:   options.Autoplay,
+		moveTime:   options.MoveTime,
+		ui:         ui.BuildUI(myEngine.Snapshot()),
+		savePath:   options.SavePath,
+		recordPath: options.RecordPath,
+	}
+
+	cont.attach(myEngine)
+
+	cont.ui.OnSwap = func(action engine.Action) {
+		err := cont.engine.PlayTurn(action)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, engine.ErrGameOver):
+			cont.engine.Tick()
+		default:
+			println(err.Error())
+		}
+	}
+
+	cont.ui.OnFrame = func() {
+		cont.engine.Tick()
+
+		cont.mutex.Lock()
+		hint := cont.hint
+		cont.mutex.Unlock()
+
+		if cont.autoplay && hint != nil && !cont.ui.IsBusy() {
+			cont.ui.OnSwap(*hint)
+		}
+	}
+
+	cont.ui.OnUndo = func() {
+		if err := cont.engine.Undo(); err != nil {
+			println(err.Error())
 		}
 	}
 
-	myEngine.HandleExplodeFinished = func(fallen [][]bool) {
-		uiInst.SetAnimStep(ui.Fall)
-		uiInst.SetAnimStart()
-		uiInst.Fallen = fallen
+	cont.ui.OnRedo = func() {
+		if err := cont.engine.Redo(); err != nil {
+			println(err.Error())
+		}
+	}
+
+	cont.ui.OnClose = func() {
+		cont.autosave()
+		cont.writeReplay()
+	}
+
+	cont.offerResume()
+
+	return cont, nil
+}
+
+// attach makes the controller and the ui play the game of an engine
+func (c *Controller) attach(myEngine *engine.Engine) {
+	c.engine = myEngine
+	// the name was checked by NewController
+	c.player, _ = ai.NewPlayer(c.playerName, myEngine)
+
+	c.ui.Reset(myEngine.Snapshot())
+
+	c.ui.TimeLeft = nil
+	if myEngine.Mode == engine.Timed {
+		c.ui.TimeLeft = myEngine.TimeLeft
 	}
 
-	myEngine.HandleExplodeFinishedNoChange = func() {
-		uiInst.SetAnimStep(ui.Idle)
-		cont.showAIMoves()
+	c.ui.Status = func(state engine.State) string {
+		return statusText(myEngine, state)
 	}
 
-	myEngine.HandleFallFinished = func(newFilled [][]bool) {
-		uiInst.Filled = newFilled
-		uiInst.SetAnimStart()
+	myEngine.Events.Subscribe(c.ui.Enqueue)
+
+	if c.recordPath != "" {
+		if c.recorder != nil {
+			c.recorder.Stop()
+		}
+		c.recorder = engine.NewRecorder(myEngine)
 	}
 
-	myEngine.HandleAddMissingCandies = func() {
-		uiInst.SetAnimStep(ui.Refill)
+	engine.On(&myEngine.Events, func(engine.TurnSettled) {
+		c.showHint()
+	})
+
+	engine.On(&myEngine.Events, func(engine.TurnUndone) {
+		c.showHint()
+	})
+
+	c.showHint()
+}
+
+func newEngine(options Options) (*engine.Engine, error) {
+	if options.LevelPath != "" {
+		return engine.LoadLevel(options.LevelPath)
 	}
 
-	myEngine.OnScoreUpdated = func(score int) {
-		uiInst.SetScore(score)
+	if options.PackName != "" {
+		return loadPackLevel(options)
 	}
 
-	myEngine.Delay = func() {
-		uiInst.Delay()
+	myEngine := &engine.Engine{}
+
+	switch options.Mode {
+	case engine.Timed:
+		myEngine.InitTimed(options.TimeLimit)
+	case engine.Zen:
+		myEngine.InitZen()
+	case engine.MoveLimited:
+		// the move limit and the objectives come with a level
+		return nil, fmt.Errorf("the %v mode needs a level, play one with -level or -pack", options.Mode)
+	default:
+		myEngine.InitRandom()
 	}
 
-	uiInst.OnSwap = func(action engine.Action) {
-		myEngine.State = myEngine.Swap(action)
+	return myEngine, nil
+}
+
+func (c *Controller) writeReplay() {
+	if c.recorder == nil {
+		return
 	}
 
-	uiInst.OnSwapFinished = func() {
-		println("Swap finished")
-		myEngine.ExplodeAndFallUntilStable()
+	if err := c.recorder.WriteFile(c.recordPath); err != nil {
+		println(fmt.Sprintf("Could not write the replay: %v", err))
+		return
 	}
 
-	return cont
+	println(fmt.Sprintf("Replay written to %s", c.recordPath))
 }
 
 func (c *Controller) Run() {
 	ui.RunUI(c.ui)
 }
 
-func (c *Controller) showAIMoves() {
-	c.ai.FindBestMove(c.engine.State)
+// showHint starts searching the move of the player in the background, the search going on is cancelled.
+// The move is shown as a hint, and played on autoplay, once the search returns.
+func (c *Controller) showHint() {
+	moveTime := c.moveTime
+	if moveTime <= 0 {
+		moveTime = DefaultMoveTime
+	}
+
+	myEngine, player := c.engine, c.player
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cancelSearch != nil {
+		c.cancelSearch()
+	}
+
+	c.hint = nil
+	c.ui.SetHint(nil, 0)
+
+	c.search++
+	search := c.search
+
+	// the player answers with its best move so far once its time is up
+	ctx, cancel := context.WithTimeout(context.Background(), moveTime)
+	c.cancelSearch = cancel
+
+	// a player searches one state at a time
+	previous := c.searchDone
+	done := make(chan struct{})
+	c.searchDone = done
+
+	go func() {
+		defer close(done)
+		defer cancel()
+
+		if previous != nil {
+			<-previous
+		}
+
+		if ctx.Err() != nil || myEngine.IsGameOver() {
+			return
+		}
+
+		hint, err := player.ChooseMove(ctx, myEngine.Snapshot())
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		// a turn was played or undone meanwhile
+		if search != c.search {
+			return
+		}
+
+		if err != nil {
+			println(err.Error())
+			return
+		}
+
+		c.hint = &hint
+		c.ui.SetHint(&hint, myEngine.HintDelay())
+	}()
+}
+
+func loadPackLevel(options Options) (*engine.Engine, error) {
+	packs, err := LoadPacks(options.PacksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pack, err := levels.Find(packs, options.PackName)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := pack.Level(options.PackLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	println(fmt.Sprintf("Playing level %d of %s: %s", options.PackLevel, pack.Title, level.Name))
+
+	return level.NewEngine()
+}
+
+// LoadPacks returns the built-in packs followed by the ones of the directory, if any
+func LoadPacks(dir string) ([]*levels.Pack, error) {
+	packs, err := levels.Builtin()
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		return packs, nil
+	}
+
+	userPacks, err := levels.LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(packs, userPacks...), nil
 }
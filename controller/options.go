@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"candycrush/engine"
+	"time"
+)
+
+type Options struct {
+	Mode      engine.GameMode
+	TimeLimit time.Duration
+	// level file to play instead of a random board
+	LevelPath string
+	// pack and level number to play, from the built-in packs or the ones of PacksDir
+	PackName  string
+	PackLevel int
+	PacksDir  string
+	// file the game is saved to on close and resumed from on launch, none when empty
+	SavePath string
+	// file the replay of the game is written to on close, none when empty
+	RecordPath string
+	// strategy of the ai player showing the hints, ai.DefaultPlayer when empty
+	Player string
+	// the ai player plays the game by itself
+	Autoplay bool
+	// thinking time of the ai player per move, DefaultMoveTime when 0
+	MoveTime time.Duration
+}
+
+const DefaultMoveTime = time.Second
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"candycrush/engine"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultSavePath returns where the game in progress is saved when the window closes
+func DefaultSavePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "candycrush", "autosave.json")
+}
+
+// autosave keeps the game in progress for the next launch, a finished game leaves nothing to resume
+func (c *Controller) autosave() {
+	// the saved game is still the one the player may resume, not the new one under the prompt
+	if c.savePath == "" || c.ui.IsAskingResume() {
+		return
+	}
+
+	if c.engine.IsGameOver() {
+		if err := os.Remove(c.savePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			println(err.Error())
+		}
+		return
+	}
+
+	if err := c.engine.SaveFile(c.savePath); err != nil {
+		println(fmt.Sprintf("Could not save the game: %v", err))
+		return
+	}
+
+	println(fmt.Sprintf("Game saved to %s", c.savePath))
+}
+
+// offerResume asks the player whether to go on with the saved game, if there is one
+func (c *Controller) offerResume() {
+	if c.savePath == "" {
+		return
+	}
+
+	save, err := engine.ReadSave(c.savePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			println(fmt.Sprintf("Could not load the saved game: %v", err))
+		}
+		return
+	}
+
+	// neither countdown runs while the player chooses: the saved game is only restored once chosen
+	c.engine.Pause()
+
+	c.ui.AskResume(func(resume bool) {
+		if !resume {
+			c.engine.Resume()
+			return
+		}
+
+		saved, err := engine.Restore(save)
+		if err != nil {
+			println(fmt.Sprintf("Could not resume the saved game: %v", err))
+			c.engine.Resume()
+			return
+		}
+
+		println("Resuming the saved game")
+		c.attach(saved)
+	})
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"candycrush/engine"
+	"fmt"
+	"strings"
+)
+
+// statusText shows the moves left and the progress of the objectives
+func statusText(e *engine.Engine, state engine.State) string {
+	var parts []string
+
+	if left := e.MovesLeft(state); left >= 0 {
+		parts = append(parts, fmt.Sprintf("Moves: %d", left))
+	}
+
+	for _, objective := range e.Rules.Objectives {
+		remaining := objective.Remaining(state)
+
+		switch objective.Kind {
+		case engine.ScoreObjective:
+			parts = append(parts, fmt.Sprintf("Score: %d left", remaining))
+		case engine.JellyObjective:
+			parts = append(parts, fmt.Sprintf("Jelly: %d left", remaining))
+		case engine.CollectObjective:
+			parts = append(parts, fmt.Sprintf("%v: %d left", objective.Color, remaining))
+		case engine.IngredientObjective:
+			parts = append(parts, fmt.Sprintf("Ingredients: %d left", remaining))
+		}
+	}
+
+	if len(e.Rules.Stars) > 0 {
+		parts = append(parts, fmt.Sprintf("Stars: %d", e.Stars(state)))
+	}
+
+	return strings.Join(parts, "  ")
+}
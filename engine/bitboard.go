@@ -0,0 +1,93 @@
+package engine
+
+import "math/bits"
+
+// the largest board a bitboard can encode: one uint64 per row, up to the height of the largest level
+const (
+	MaxBitboardWidth  = 64
+	MaxBitboardHeight = MaxLevelSize
+)
+
+// RowMask has one bit per cell: bit x of row y is the cell x, y
+type RowMask [MaxBitboardHeight]uint64
+
+// Bitboard encodes the candies of a board as one mask per color: bit x of Colors[color][y] is set
+// when the cell x, y holds a candy of that color. Blockers, voids and ingredients are in no mask.
+// It is a fixed size value, so that building one does not allocate.
+type Bitboard struct {
+	Width  int
+	Height int
+	Colors [NumColors + 1]RowMask
+}
+
+// FitsBitboard tells whether the board of the state is small enough for a bitboard
+func FitsBitboard(state State) bool {
+	return state.Width() <= MaxBitboardWidth && state.Height() <= MaxBitboardHeight
+}
+
+// NewBitboard encodes the candies of a state, which must fit a bitboard
+func NewBitboard(state State) Bitboard {
+	b := Bitboard{Width: state.Width(), Height: state.Height()}
+
+	for y := 0; y < b.Height; y++ {
+		for x, cell := range state.Board.Cells[y*b.Width : (y+1)*b.Width] {
+			if cell.IsCandy() {
+				b.Colors[cell.Color()][y] |= 1 << x
+			}
+		}
+	}
+
+	return b
+}
+
+// Matches returns the mask of the candies in a line of three or more of the same color
+func (b *Bitboard) Matches() RowMask {
+	var matches RowMask
+
+	for color := Red; color <= Orange; color++ {
+		rows := &b.Colors[color]
+
+		for y := 0; y < b.Height; y++ {
+			row := rows[y]
+
+			// the bits starting three in a row
+			starts := row & (row >> 1) & (row >> 2)
+			matches[y] |= starts | starts<<1 | starts<<2
+
+			if y+2 < b.Height {
+				column := row & rows[y+1] & rows[y+2]
+				matches[y] |= column
+				matches[y+1] |= column
+				matches[y+2] |= column
+			}
+		}
+	}
+
+	return matches
+}
+
+// Coords translates a mask back to the coordinates of the board
+func (b *Bitboard) Coords(mask RowMask) []Coord {
+	var coords []Coord
+
+	for y := 0; y < b.Height; y++ {
+		for row := mask[y]; row != 0; row &= row - 1 {
+			coords = append(coords, Coord{X: bits.TrailingZeros64(row), Y: y})
+		}
+	}
+
+	return coords
+}
+
+// flags translates a mask back to flags in the order of Board.Cells
+func (b *Bitboard) flags(mask RowMask) []bool {
+	flags := make([]bool, b.Width*b.Height)
+
+	for y := 0; y < b.Height; y++ {
+		for row := mask[y]; row != 0; row &= row - 1 {
+			flags[y*b.Width+bits.TrailingZeros64(row)] = true
+		}
+	}
+
+	return flags
+}
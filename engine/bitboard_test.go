@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+// randomBoard fills a board with few colors so that it has matches, and with every other kind of cell
+func randomBoard(random *Random, width, height int) State {
+	state := State{Board: NewBoard(width, height)}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var cell Cell
+
+			switch kind := random.Intn(20); {
+			case kind == 0:
+				cell = NewBlocker(random.Intn(MaxBlockerHP) + 1)
+			case kind == 1:
+				cell = Void
+			case kind == 2:
+				cell = Ingredient
+			case kind == 3:
+				cell = Empty
+			default:
+				cell = AllColors[random.Intn(3)]
+				if random.Intn(5) == 0 {
+					cell = cell.WithSpecial(TimeBonus)
+				}
+			}
+
+			state.SetCell(Coord{X: x, Y: y}, cell)
+		}
+	}
+
+	return state
+}
+
+func TestBitboardMatchesScalarScan(t *testing.T) {
+	e := &Engine{}
+	random := NewRandom(1)
+	found := 0
+
+	for i := 0; i < 5000; i++ {
+		width := random.Intn(MaxBitboardWidth) + 1
+		height := random.Intn(MaxBitboardHeight) + 1
+		if i%2 == 0 {
+			// the usual sizes
+			width, height = random.Intn(10)+3, random.Intn(10)+3
+		}
+
+		state := randomBoard(&random, width, height)
+
+		expected := make([]bool, width*height)
+		e.findExplodingIn(state, Coord{}, Coord{X: width - 1, Y: height - 1}, expected)
+
+		bitboard := NewBitboard(state)
+		matches := bitboard.Matches()
+
+		if flags := bitboard.flags(matches); !slices.Equal(flags, expected) {
+			t.Fatalf("%dx%d board, the bitboard finds\n%v\nthe scan\n%v\non\n%v", width, height, flags, expected, state)
+		}
+
+		coords := bitboard.Coords(matches)
+		for _, c := range coords {
+			if !expected[state.Board.index(c)] {
+				t.Fatalf("%dx%d board, %v is not in a match\n%v", width, height, c, state)
+			}
+		}
+
+		if !slices.Equal(e.findAllExploding(state), expected) {
+			t.Fatalf("%dx%d board, findAllExploding differs from the scan\n%v", width, height, state)
+		}
+
+		found += len(coords)
+	}
+
+	if found == 0 {
+		t.Fatal("no board had a match")
+	}
+}
@@ -1,15 +1,95 @@
 package engine
 
+// Board must be changed through SetCell and SetJelly, which keep its hash up to date
 type Board struct {
 	Width  int
 	Height int
-	Cells  [][]Cell
+	// row after row: the cell x, y is at y*Width+x
+	Cells []Cell
+	// layers of jelly under the cells, in the same order, nil when the board has none
+	Jelly []int
+	hash  uint64
+	// cells changed since the last match scan, and since the valid moves were last listed
+	matchDirty region
+	moveDirty  region
+	// hash of the board when the valid moves were last listed
+	movesBase uint64
+}
+
+// NewBoard returns a board of empty cells without jelly
+func NewBoard(width, height int) Board {
+	return Board{
+		Width:  width,
+		Height: height,
+		Cells:  make([]Cell, width*height),
+	}
+}
+
+func (b *Board) index(coord Coord) int {
+	return coord.Y*b.Width + coord.X
 }
 
 func (b *Board) SetCell(coord Coord, cell Cell) {
-	b.Cells[coord.Y][coord.X] = cell
+	i := b.index(coord)
+	if b.Cells[i] == cell {
+		return
+	}
+
+	b.hash ^= cellKey(coord, b.Cells[i]) ^ cellKey(coord, cell)
+	b.Cells[i] = cell
+	b.matchDirty.add(coord)
+	b.moveDirty.add(coord)
 }
 
 func (b *Board) GetCell(coord Coord) Cell {
-	return b.Cells[coord.Y][coord.X]
+	return b.Cells[b.index(coord)]
+}
+
+func (b *Board) GetJelly(coord Coord) int {
+	if b.Jelly == nil {
+		return 0
+	}
+	return b.Jelly[b.index(coord)]
+}
+
+func (b *Board) SetJelly(coord Coord, layers int) {
+	if b.Jelly == nil {
+		b.Jelly = make([]int, b.Width*b.Height)
+	}
+
+	i := b.index(coord)
+	b.hash ^= zobrist(coord, jellyComponent, b.Jelly[i]) ^ zobrist(coord, jellyComponent, layers)
+	b.Jelly[i] = layers
+}
+
+// JellyLeft returns the layers of jelly still on the board
+func (b *Board) JellyLeft() int {
+	left := 0
+	for _, layers := range b.Jelly {
+		left += layers
+	}
+	return left
+}
+
+// isConsistent tells whether the layers have the size of the board, as expected from a decoded board
+func (b *Board) isConsistent() bool {
+	return b.Width > 0 && b.Height > 0 && len(b.Cells) == b.Width*b.Height &&
+		(b.Jelly == nil || len(b.Jelly) == len(b.Cells))
+}
+
+// copyFrom makes the board a copy of another one, reusing its slices when they are large enough
+func (b *Board) copyFrom(other *Board) {
+	b.Width = other.Width
+	b.Height = other.Height
+	b.hash = other.hash
+	b.matchDirty = other.matchDirty
+	b.moveDirty = other.moveDirty
+	b.movesBase = other.movesBase
+	b.Cells = append(b.Cells[:0], other.Cells...)
+
+	if other.Jelly == nil {
+		b.Jelly = nil
+	} else {
+		b.Jelly = append(b.Jelly[:0], other.Jelly...)
+	}
 }
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"sort"
+	"sync"
+)
+
+// Bus dispatches the game events to every subscribed listener, in subscription order
+type Bus struct {
+	mutex     sync.Mutex
+	nextID    int
+	listeners map[int]func(Event)
+}
+
+func (b *Bus) Subscribe(listener func(Event)) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.listeners == nil {
+		b.listeners = map[int]func(Event){}
+	}
+
+	b.nextID++
+	b.listeners[b.nextID] = listener
+
+	return b.nextID
+}
+
+func (b *Bus) Unsubscribe(id int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.listeners, id)
+}
+
+// Publish calls the listeners synchronously, outside the lock so that they can (un)subscribe
+func (b *Bus) Publish(event Event) {
+	for _, listener := range b.snapshot() {
+		listener(event)
+	}
+}
+
+func (b *Bus) snapshot() []func(Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ids := make([]int, 0, len(b.listeners))
+	for id := range b.listeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	listeners := make([]func(Event), len(ids))
+	for i, id := range ids {
+		listeners[i] = b.listeners[id]
+	}
+
+	return listeners
+}
+
+// On subscribes a listener to a single type of event
+func On[T Event](b *Bus, listener func(T)) int {
+	return b.Subscribe(func(event Event) {
+		if typed, ok := event.(T); ok {
+			listener(typed)
+		}
+	})
+}
@@ -10,4 +10,67 @@ const (
 	Blue
 	Purple
 	Orange
+	// Blocker never matches and loses a hit point when a neighbor explodes
+	Blocker
+	// Void is a hole in the board, candies fall through it
+	Void
+	// Ingredient never matches and is collected when it reaches the bottom of its column
+	Ingredient
 )
+
+const NumColors = 6
+
+var AllColors = []Cell{Red, Yellow, Green, Blue, Purple, Orange}
+
+// Special is stored in the bits above the color of a cell
+type Special int
+
+const (
+	NoSpecial Special = iota
+	TimeBonus
+)
+
+const colorBits = 4
+const colorMask = 1<<colorBits - 1
+const specialBits = 4
+const specialMask = 1<<specialBits - 1
+
+// the hit points of a blocker are stored above the special, minus one so that a plain Blocker has one
+const hpShift = colorBits + specialBits
+const hpMask = 1<<4 - 1
+
+// MaxBlockerHP is the most hit points a blocker can have, so that it is written with one digit
+const MaxBlockerHP = 9
+
+func (c Cell) Color() Cell {
+	return c & colorMask
+}
+
+func (c Cell) IsCandy() bool {
+	return c.Color() >= Red && c.Color() <= Orange
+}
+
+// IsMovable tells whether the cell can be swapped
+func (c Cell) IsMovable() bool {
+	return c.IsCandy() || c.Color() == Ingredient
+}
+
+func (c Cell) Special() Special {
+	return Special((c >> colorBits) & specialMask)
+}
+
+func (c Cell) WithSpecial(special Special) Cell {
+	return c&^(specialMask<<colorBits) | Cell(special)<<colorBits
+}
+
+// HP returns the hit points of a blocker, 0 for any other cell
+func (c Cell) HP() int {
+	if c.Color() != Blocker {
+		return 0
+	}
+	return int((c>>hpShift)&hpMask) + 1
+}
+
+func NewBlocker(hp int) Cell {
+	return Blocker | Cell(hp-1)<<hpShift
+}
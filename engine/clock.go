@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock gives the current time to the engine, so that timed games can be faked in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type RealClock struct{}
+
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock only moves forward when Advance is called
+type ManualClock struct {
+	mutex   sync.Mutex
+	current time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{current: start}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.current
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.current = c.current.Add(d)
+}
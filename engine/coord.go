@@ -12,3 +12,9 @@ func GetNeighbor(dir Direction, from Coord) Coord {
 	dest := Coord{X: destX, Y: destY}
 	return dest
 }
+
+func (c Coord) IsAdjacent(other Coord) bool {
+	dx := c.X - other.X
+	dy := c.Y - other.Y
+	return dx*dx+dy*dy == 1
+}
@@ -1,41 +1,58 @@
 package engine
 
 import (
-	"fmt"
-	"math/rand"
+	"sync"
+	"time"
 )
 
 /**
  * Candy crush engine (implemented only game logic)
  */
 
+// Engine owns the live game state: after the Init functions, it is only read and written under its mutex
 type Engine struct {
-	State                         State
-	HandleChangedAfterExplode     func(changed bool, exploded [][]bool)
-	HandleExplodeFinished         func(fallen [][]bool)
-	HandleExplodeFinishedNoChange func()
-	HandleFallFinished            func(newFilled [][]bool)
-	HandleAddMissingCandies       func()
-	Delay                         func()
-	OnScoreUpdated                func(score int)
+	state     State
+	mutex     sync.Mutex
+	resolving bool
+	Seed      uint64
+	Events    Bus
+	Mode      GameMode
+	Clock     Clock
+	TimeLimit time.Duration
+	startedAt time.Time
+	// the countdown is paused since then, when not zero
+	pausedAt time.Time
+	// time spent paused before pausedAt
+	paused time.Duration
+	timeUp bool
+	// no valid move is left on the board, even after a shuffle
+	noMoves bool
+	// GameOver is published only once
+	gameOverPublished bool
+	history           History
+	HistoryLimit      int
+	// valid moves of the state, updated on commit
+	moves MoveCache
+	Rules Rules
 }
 
+// FindValidMoves returns the swaps that make a match
 func (e *Engine) FindValidMoves(state State) []Action {
-	var validMoves []Action
+	return e.validMovesIn(state, Coord{}, Coord{X: state.Width() - 1, Y: state.Height() - 1}, nil)
+}
 
-	for i := 0; i < state.Height(); i++ {
-		for j := 0; j < state.Width(); j++ {
-			if i > 0 {
-				validMoves = append(validMoves, Action{From: Coord{X: j, Y: i}, To: Coord{X: j, Y: i - 1}})
-			}
-			if i < state.Height()-1 {
-				validMoves = append(validMoves, Action{From: Coord{X: j, Y: i}, To: Coord{X: j, Y: i + 1}})
-			}
-			if j > 0 {
-				validMoves = append(validMoves, Action{From: Coord{X: j, Y: i}, To: Coord{X: j - 1, Y: i}})
-			}
-			if j < state.Width()-1 {
-				validMoves = append(validMoves, Action{From: Coord{X: j, Y: i}, To: Coord{X: j + 1, Y: i}})
+// validMovesIn appends the valid moves starting from the cells of a box, in the order of FindValidMoves
+func (e *Engine) validMovesIn(state State, from, to Coord, validMoves []Action) []Action {
+	for i := from.Y; i <= to.Y; i++ {
+		for j := from.X; j <= to.X; j++ {
+			c := Coord{X: j, Y: i}
+
+			for _, dir := range []Direction{Up, Down, Left, Right} {
+				action := Action{From: c, To: GetNeighbor(dir, c)}
+
+				if e.checkAction(state, action) == nil {
+					validMoves = append(validMoves, action)
+				}
 			}
 		}
 	}
@@ -43,307 +60,419 @@ func (e *Engine) FindValidMoves(state State) []Action {
 	return validMoves
 }
 
-func (e *Engine) Init() State {
-
-	width := 9
-	height := 9
+// FindHint returns the first move that creates a match
+func (e *Engine) FindHint(state State) (Action, bool) {
+	validMoves := e.FindValidMoves(state)
 
-	board := Board{
-		Width:  width,
-		Height: height,
-		Cells:  make([][]Cell, height),
+	if len(validMoves) == 0 {
+		return Action{}, false
 	}
 
-	for i := 0; i < height; i++ {
-		board.Cells[i] = make([]Cell, width)
-		for j := 0; j < width; j++ {
-			c := Coord{X: j, Y: i}
-			board.SetCell(c, Empty)
-		}
-	}
+	return validMoves[0], true
+}
+
+func (e *Engine) Init() State {
+	return newState(9, 9)
+}
 
+func newState(width, height int) State {
 	return State{
-		Board: board,
+		Board: NewBoard(width, height),
 		Score: 0,
 	}
 }
 
 func (e *Engine) InitRandom() {
+	e.initBoard(e.Init())
+}
 
-	e.State = e.Init()
+// initBoard fills the empty cells of a board with candies that do not make a match
+func (e *Engine) initBoard(state State) {
+	e.state = state
+	e.state.Rand = NewRandom(e.seed())
 
-	for i := 0; i < e.State.Height(); i++ {
-		for j := 0; j < e.State.Width(); j++ {
+	for i := 0; i < e.state.Height(); i++ {
+		for j := 0; j < e.state.Width(); j++ {
 			c := Coord{X: j, Y: i}
-			e.State.SetCell(c, e.randomCell())
+			if e.state.GetCell(c) != Empty {
+				continue
+			}
+
+			for attempt := 0; attempt < 10; attempt++ {
+				e.state.SetCell(c, e.randomCandy(&e.state))
+				if !e.makesMatch(e.state, c) {
+					break
+				}
+			}
 		}
 	}
 
-	if e.State.Board.Width <= 0 || e.State.Board.Height <= 0 {
-		panic("Invalid board size")
+	// matches left by a designed board
+	e.state = e.resolve(e.state, nil)
+
+	e.state.Score = 0
+	e.state.BonusSeconds = 0
+	e.state.Cleared = [NumColors + 1]int{}
+	e.state.Ingredients = 0
+	e.noMoves = len(e.ValidMoves(&e.state, &e.moves)) == 0
+}
+
+func (e *Engine) seed() uint64 {
+	if e.Seed != 0 {
+		return e.Seed
+	}
+	return uint64(time.Now().UnixNano())
+}
+
+func (e *Engine) randomCell(state *State) Cell {
+	if state.Rand.Float64() < e.blockerChance(state.Score) {
+		return Blocker
 	}
 
-	e.ExplodeAndFallUntilStableSync()
+	return e.randomCandy(state)
+}
 
-	e.State.Score = 0
+func (e *Engine) randomCandy(state *State) Cell {
+	colors := e.colors(state.Score)
+	cell := colors[state.Rand.Intn(len(colors))]
+
+	if e.Rules.Refill.TimeBonusChance > 0 && state.Rand.Float64() < e.Rules.Refill.TimeBonusChance {
+		cell = cell.WithSpecial(TimeBonus)
+	}
+
+	return cell
 }
 
-func (e *Engine) randomCell() Cell {
-	return Cell(rand.Intn(6) + 1)
+// makesMatch tells whether the cell is part of a line of three
+func (e *Engine) makesMatch(state State, coord Coord) bool {
+	return lineOfThree(&state, coord, state.GetCell)
 }
 
-func (e *Engine) isValidAction(action Action) error {
-	if action.From.X < 0 || action.From.X >= e.State.Width() || action.From.Y < 0 || action.From.Y >= e.State.Height() {
-		return fmt.Errorf("invalid action: %v: out of bounds (from)", action)
+// lineOfThree tells whether the candy at coord is part of a line of three candies of its color,
+// reading the cells through cellAt
+func lineOfThree(state *State, coord Coord, cellAt func(Coord) Cell) bool {
+	cell := cellAt(coord)
+	if !cell.IsCandy() {
+		return false
 	}
 
-	if action.To.X < 0 || action.To.X >= e.State.Width() || action.To.Y < 0 || action.To.Y >= e.State.Height() {
-		return fmt.Errorf("invalid action: %v: out of bounds (to)", action)
+	color := cell.Color()
+
+	sameColor := func(c Coord) bool {
+		return state.IsInside(c) && cellAt(c).IsCandy() && cellAt(c).Color() == color
 	}
 
-	if action.From.X == action.To.X && action.From.Y == action.To.Y {
-		return fmt.Errorf("invalid action: %v: same cell", action)
+	count := func(dir Direction) int {
+		n := 0
+		for c := GetNeighbor(dir, coord); sameColor(c); c = GetNeighbor(dir, c) {
+			n++
+		}
+		return n
 	}
 
-	if action.From.X != action.To.X && action.From.Y != action.To.Y {
-		return fmt.Errorf("invalid action: %v: not adjacent", action)
+	return count(Left)+count(Right) >= 2 || count(Up)+count(Down) >= 2
+}
+
+// swapMakesMatch tells whether swapping the cells of the action lines up three candies, without changing the state
+func (e *Engine) swapMakesMatch(state State, action Action) bool {
+	cellAt := func(c Coord) Cell {
+		switch c {
+		case action.From:
+			return state.GetCell(action.To)
+		case action.To:
+			return state.GetCell(action.From)
+		}
+		return state.GetCell(c)
+	}
+
+	return lineOfThree(&state, action.From, cellAt) || lineOfThree(&state, action.To, cellAt)
+}
+
+func (e *Engine) isValidAction(state State, action Action) error {
+	if err := e.checkAction(state, action); err != nil {
+		return &ActionError{Action: action, Err: err}
+	}
+
+	return nil
+}
+
+// checkAction returns the sentinel error rejecting an action, it does not allocate
+func (e *Engine) checkAction(state State, action Action) error {
+	if !state.IsInside(action.From) || !state.IsInside(action.To) {
+		return ErrOutOfBounds
 	}
 
-	if e.State.GetCell(action.From) == Empty || e.State.GetCell(action.To) == Empty {
-		return fmt.Errorf("invalid action: %v: empty cell", action)
+	if !action.From.IsAdjacent(action.To) {
+		return ErrNotAdjacent
+	}
+
+	if state.GetCell(action.From) == Empty || state.GetCell(action.To) == Empty {
+		return ErrEmptyCell
+	}
+
+	if !state.GetCell(action.From).IsMovable() || !state.GetCell(action.To).IsMovable() {
+		return ErrBlockedCell
+	}
+
+	if !e.swapMakesMatch(state, action) {
+		return ErrNoMatch
 	}
 
 	return nil
 }
 
-func (e *Engine) Swap(action Action) State {
-	if err := e.isValidAction(action); err != nil {
-		println(fmt.Sprintf("Invalid action: %v: %v", action, err))
-		return e.State
+// Snapshot returns a copy of the current state, safe to keep and read from any goroutine
+func (e *Engine) Snapshot() State {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.state.clone()
+}
+
+// Swap returns the current state with the cells of the action swapped, or the zero state when the action is not valid
+func (e *Engine) Swap(action Action) (State, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.isGameOver() {
+		return State{}, &ActionError{Action: action, Err: ErrGameOver}
+	}
+
+	if err := e.isValidAction(e.state, action); err != nil {
+		return State{}, err
 	}
 
-	state := e.State.clone()
+	state := e.state.clone()
 	state.SwapCells(action.From, action.To)
 
-	return state
+	return state, nil
 }
 
-func (e *Engine) findAllExploding(state State) [][]bool {
-	exploding := make([][]bool, state.Height())
+// findAllExploding flags the cells of the lines of three or more, in the order of Board.Cells.
+// Only the lines touching the cells changed since the last scan are checked, the others cannot have changed.
+func (e *Engine) findAllExploding(state State) []bool {
+	if state.Board.matchDirty.isFull() && FitsBitboard(state) {
+		bitboard := NewBitboard(state)
+		return bitboard.flags(bitboard.Matches())
+	}
 
-	for i := 0; i < state.Height(); i++ {
-		exploding[i] = make([]bool, state.Width())
+	exploding := make([]bool, len(state.Board.Cells))
+
+	from, to, ok := state.Board.matchDirty.bounds(&state.Board, 0)
+	if ok {
+		e.findExplodingIn(state, from, to, exploding)
+	}
+
+	return exploding
+}
+
+// findExplodingIn flags the lines of three or more with a cell in the box
+func (e *Engine) findExplodingIn(state State, from, to Coord, exploding []bool) {
+	width := state.Width()
+
+	sameColor := func(i, j, k int) bool {
+		cell := state.Board.Cells[i]
+		return cell.IsCandy() && cell.Color() == state.Board.Cells[j].Color() && cell.Color() == state.Board.Cells[k].Color()
 	}
 
 	// Explode rows
-	for i := 0; i < state.Height(); i++ {
-		for j := 0; j < state.Width()-2; j++ {
-			c := Coord{X: j, Y: i}
-			c2 := Coord{X: j + 1, Y: i}
-			c3 := Coord{X: j + 2, Y: i}
-			if state.GetCell(c) != Empty && state.GetCell(c) == state.GetCell(c2) && state.GetCell(c) == state.GetCell(c3) {
-				exploding[i][j] = true
-				exploding[i][j+1] = true
-				exploding[i][j+2] = true
+	for i := from.Y; i <= to.Y; i++ {
+		for j := max(from.X-2, 0); j <= min(to.X, width-3); j++ {
+			c := i*width + j
+			if sameColor(c, c+1, c+2) {
+				exploding[c] = true
+				exploding[c+1] = true
+				exploding[c+2] = true
 			}
 		}
 	}
 
 	// Explode columns
-	for i := 0; i < state.Board.Height-2; i++ {
-		for j := 0; j < state.Board.Width; j++ {
-			c := Coord{X: j, Y: i}
-			c2 := Coord{X: j, Y: i + 1}
-			c3 := Coord{X: j, Y: i + 2}
-			if state.GetCell(c) != Empty && state.GetCell(c) == state.GetCell(c2) && state.GetCell(c) == state.GetCell(c3) {
-				exploding[i][j] = true
-				exploding[i+1][j] = true
-				exploding[i+2][j] = true
+	for i := max(from.Y-2, 0); i <= min(to.Y, state.Height()-3); i++ {
+		for j := from.X; j <= to.X; j++ {
+			c := i*width + j
+			if sameColor(c, c+width, c+2*width) {
+				exploding[c] = true
+				exploding[c+width] = true
+				exploding[c+2*width] = true
 			}
 		}
 	}
-
-	return exploding
 }
 
 /*
  * Explode candies (if there are 3 or more in a row or column)
+ * Also damages the blockers next to them and collects the ingredients at the bottom
  */
-func (e *Engine) explode(state State) (State, [][]bool) {
+func (e *Engine) explode(state State) (State, []Coord) {
 	newState := state.clone()
 
-	score := 0
-
-	exploding := e.findAllExploding(newState)
+	var exploded []Coord
+	e.explodeInPlace(&newState, &exploded)
 
-	// Explode candies
-	for i := 0; i < newState.Height(); i++ {
-		for j := 0; j < newState.Width(); j++ {
-			c := Coord{X: j, Y: i}
-			if exploding[i][j] {
-				newState.SetCell(c, Empty)
-				score++
-			}
-		}
-	}
+	return newState, exploded
+}
 
-	// Update score
-	newState.Score += score
+// explodeInPlace returns the number of exploded cells, their coordinates are appended to exploded when it is not nil
+func (e *Engine) explodeInPlace(state *State, exploded *[]Coord) int {
+	exploding := e.findAllExploding(*state)
+	// the changes made by the explosion are the ones to check next time
+	state.Board.matchDirty.reset()
 
-	return newState, exploding
-}
+	e.damageBlockers(state, exploding)
+	e.addCollectedIngredients(state, exploding)
 
-func (e *Engine) ExplodeAndScore(state State) (State, bool, [][]bool) {
-	changed := false
+	count := 0
 
-	newState, exploded := e.explode(state)
-	if newState.Score != state.Score {
-		changed = true
-		state = newState
-	}
+	// Explode candies
+	for i := 0; i < state.Height(); i++ {
+		for j := 0; j < state.Width(); j++ {
+			c := Coord{X: j, Y: i}
+			if !exploding[state.Board.index(c)] {
+				continue
+			}
 
-	println(fmt.Sprintf("Score: %d", state.Score))
+			cell := state.GetCell(c)
 
-	return state, changed, exploded
-}
+			switch {
+			case cell.IsCandy():
+				state.Cleared[cell.Color()]++
+			case cell.Color() == Ingredient:
+				state.Ingredients++
+			}
 
-/*
-Fall candies: move candies down to fill empty cells
-*/
-func (e *Engine) Fall(state State) (State, [][]bool) {
+			if cell.Special() == TimeBonus {
+				state.BonusSeconds += TimeBonusSeconds
+			}
 
-	newState := state.clone()
+			if jelly := state.Board.GetJelly(c); jelly > 0 {
+				state.Board.SetJelly(c, jelly-1)
+			}
 
-	fallen := make([][]bool, newState.Height())
-	for i := 0; i < newState.Height(); i++ {
-		fallen[i] = make([]bool, newState.Width())
-	}
+			state.SetCell(c, Empty)
+			count++
 
-	for j := 0; j < newState.Width(); j++ {
-		for i := newState.Height() - 1; i >= 0; i-- {
-			c := Coord{X: j, Y: i}
-			if newState.GetCell(c) == Empty {
-				for k := i - 1; k >= 0; k-- {
-					c2 := Coord{X: j, Y: k}
-					if newState.GetCell(c2) != Empty {
-						newState.SetCell(c, newState.GetCell(c2))
-						fallen[i][j] = true
-						newState.SetCell(c2, Empty)
-						break
-					}
-				}
+			if exploded != nil {
+				*exploded = append(*exploded, c)
 			}
 		}
 	}
 
-	return newState, fallen
+	return count
 }
 
-func (e *Engine) ExplodeAndFallUntilStable() {
-	// explode while possible
-	newGameState, changed, exploded := e.ExplodeAndScore(e.State)
+// damageBlockers removes a hit point from the blockers next to an exploding candy, the ones left without any explode
+func (e *Engine) damageBlockers(state *State, exploding []bool) {
+	var blockers []Coord
 
-	if e.HandleChangedAfterExplode != nil {
-		e.HandleChangedAfterExplode(changed, exploded)
-	}
+	for i := 0; i < state.Height(); i++ {
+		for j := 0; j < state.Width(); j++ {
+			c := Coord{X: j, Y: i}
+			if state.GetCell(c).Color() != Blocker {
+				continue
+			}
 
-	if changed {
-		go func() {
-			e.Delay()
-			e.State = newGameState
-			e.OnScoreUpdated(e.State.Score)
-			e.onExplodeFinished(changed)
-		}()
+			for _, dir := range []Direction{Up, Down, Left, Right} {
+				n := GetNeighbor(dir, c)
+				if state.IsInside(n) && exploding[state.Board.index(n)] {
+					blockers = append(blockers, c)
+					break
+				}
+			}
+		}
 	}
-}
-
-func (e *Engine) ExplodeAndFallUntilStableSync() {
-	// explode while possible
-	for {
-		newGameState, changed, _ := e.ExplodeAndScore(e.State)
-		e.State = newGameState
-
-		if changed {
-			newGameState, _ := e.Fall(e.State)
-			e.State = newGameState
 
-			// add missing candies
-			newGameState2, _ := e.AddMissingCandies(e.State)
-			e.State = newGameState2
+	for _, c := range blockers {
+		if hp := state.GetCell(c).HP(); hp > 1 {
+			state.SetCell(c, NewBlocker(hp-1))
 		} else {
-			println("No more explosions for this loop")
-			break
+			exploding[state.Board.index(c)] = true
 		}
 	}
-
-	println("Explode and fall until stable finished")
 }
 
-func (e *Engine) onExplodeFinished(explodedChanged bool) {
-	println("Explode finished")
-
-	if explodedChanged {
-		newGameState, fallen := e.Fall(e.State)
-
-		if e.HandleExplodeFinished != nil {
-			e.HandleExplodeFinished(fallen)
+func (e *Engine) addCollectedIngredients(state *State, exploding []bool) {
+	for i := 0; i < state.Height(); i++ {
+		for j := 0; j < state.Width(); j++ {
+			c := Coord{X: j, Y: i}
+			if state.GetCell(c) == Ingredient && state.IsExit(c) {
+				exploding[state.Board.index(c)] = true
+			}
 		}
-
-		go func() {
-			e.State = newGameState
-			e.Delay()
-			e.onFallFinished()
-		}()
-	} else {
-		e.HandleExplodeFinishedNoChange()
 	}
 }
 
-func (e *Engine) onFallFinished() {
-	println("Fall finished")
-
-	// add missing candies
-	newGameState, newFilled := e.AddMissingCandies(e.State)
-	e.State = newGameState
-	e.HandleFallFinished(newFilled)
-
-	go func() {
-		e.Delay()
-		e.onAddMissingCandiesFinished()
-	}()
-}
+/*
+Fall candies: move candies down to fill empty cells
+*/
+func (e *Engine) fall(state State) (State, []Move) {
+	newState := state.clone()
 
-func (e *Engine) onAddMissingCandiesFinished() {
-	println("Add missing candies finished")
+	var moves []Move
+	e.fallInPlace(&newState, &moves)
 
-	go func() {
-		e.Delay()
-		e.ExplodeAndFallUntilStable()
-	}()
+	return newState, moves
 }
 
-func (e *Engine) AddMissingCandies(state State) (State, [][]bool) {
-	if e.HandleAddMissingCandies != nil {
-		e.HandleAddMissingCandies()
+// fallInPlace appends the fallen candies to moves when it is not nil
+func (e *Engine) fallInPlace(state *State, moves *[]Move) {
+	for j := 0; j < state.Width(); j++ {
+		for i := state.Height() - 1; i >= 0; i-- {
+			c := Coord{X: j, Y: i}
+			if state.GetCell(c) == Empty {
+				for k := i - 1; k >= 0; k-- {
+					c2 := Coord{X: j, Y: k}
+					// candies fall through the voids
+					if state.GetCell(c2) != Empty && state.GetCell(c2) != Void {
+						state.SetCell(c, state.GetCell(c2))
+						if moves != nil {
+							*moves = append(*moves, Move{From: c2, To: c})
+						}
+						state.SetCell(c2, Empty)
+						break
+					}
+				}
+			}
+		}
 	}
+}
 
+func (e *Engine) refill(state State) (State, []Coord) {
 	newState := state.clone()
 
-	newFilledCellsTmp := make([][]bool, newState.Height())
-	for i := 0; i < newState.Height(); i++ {
-		newFilledCellsTmp[i] = make([]bool, newState.Width())
+	var filled []Coord
+	e.refillInPlace(&newState, &filled)
+
+	return newState, filled
+}
+
+// refillInPlace appends the refilled cells to filled when it is not nil
+func (e *Engine) refillInPlace(state *State, filled *[]Coord) {
+	ingredients := 0
+	if e.Rules.Refill.IngredientChance > 0 {
+		ingredients = e.ingredientsToSpawn(*state)
 	}
 
-	for j := 0; j < newState.Width(); j++ {
-		for i := 0; i < newState.Height(); i++ {
+	for j := 0; j < state.Width(); j++ {
+		for i := 0; i < state.Height(); i++ {
 			c := Coord{X: j, Y: i}
-			if newState.GetCell(c) == Empty {
-				newState.SetCell(c, e.randomCell())
-
-				newFilledCellsTmp[i][j] = true
+			if state.GetCell(c) == Empty {
+				if ingredients > 0 && state.Rand.Float64() < e.Rules.Refill.IngredientChance {
+					state.SetCell(c, Ingredient)
+					ingredients--
+				} else {
+					state.SetCell(c, e.randomCell(state))
+				}
+				if filled != nil {
+					*filled = append(*filled, c)
+				}
 			}
 		}
 	}
+}
 
-	return newState, newFilledCellsTmp
+func (e *Engine) commit(state State) {
+	e.checkTimeUp()
+	e.state = state
+	e.noMoves = len(e.ValidMoves(&e.state, &e.moves)) == 0
 }
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOutOfBounds = errors.New("out of bounds")
+	ErrNotAdjacent = errors.New("cells are not adjacent")
+	ErrEmptyCell   = errors.New("empty cell")
+	ErrBlockedCell = errors.New("blocker cell")
+	ErrNoMatch     = errors.New("swap does not make a match")
+	ErrGameOver    = errors.New("game over")
+	ErrBusy        = errors.New("busy resolving the previous turn")
+
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// ActionError is returned for a rejected action, errors.Is matches its reason
+type ActionError struct {
+	Action Action
+	Err    error
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("invalid action %v: %v", e.Action, e.Err)
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,102 @@
+package engine
+
+// Event is published on the engine bus while a turn is played
+type Event interface {
+	isEvent()
+}
+
+type SwapCommitted struct {
+	Action Action
+	State  State
+}
+
+type MatchesExploded struct {
+	Cells []Coord
+	State State
+}
+
+type CandiesFell struct {
+	Moves []Move
+	State State
+}
+
+type BoardRefilled struct {
+	Cells []Coord
+	State State
+}
+
+// BoardShuffled moves the candies of a board left without any valid move
+type BoardShuffled struct {
+	Cells []Coord
+	State State
+}
+
+type ScoreChanged struct {
+	Delta int
+	Score int
+}
+
+type TurnSettled struct {
+	State State
+}
+
+type GameOver struct {
+	State State
+	// the objectives are reached
+	Won   bool
+	Stars int
+}
+
+// TurnUndone replaces the whole state, without any animation
+type TurnUndone struct {
+	Action Action
+	State  State
+}
+
+func (SwapCommitted) isEvent()   {}
+func (MatchesExploded) isEvent() {}
+func (CandiesFell) isEvent()     {}
+func (BoardRefilled) isEvent()   {}
+func (BoardShuffled) isEvent()   {}
+func (ScoreChanged) isEvent()    {}
+func (TurnSettled) isEvent()     {}
+func (GameOver) isEvent()        {}
+func (TurnUndone) isEvent()      {}
+
+func (e MatchesExploded) Mask() [][]bool {
+	return cellsMask(e.State, e.Cells)
+}
+
+func (e CandiesFell) Mask() [][]bool {
+	destinations := make([]Coord, len(e.Moves))
+	for i, m := range e.Moves {
+		destinations[i] = m.To
+	}
+	return cellsMask(e.State, destinations)
+}
+
+func (e BoardRefilled) Mask() [][]bool {
+	return cellsMask(e.State, e.Cells)
+}
+
+func (e BoardShuffled) Mask() [][]bool {
+	return cellsMask(e.State, e.Cells)
+}
+
+// phaseEvent converts a phase of a timeline to the event announcing it
+func phaseEvent(phase Phase, action Action) Event {
+	switch phase.Kind {
+	case SwapPhase:
+		return SwapCommitted{Action: action, State: phase.State}
+	case ExplodePhase:
+		return MatchesExploded{Cells: phase.Cells, State: phase.State}
+	case ScorePhase:
+		return ScoreChanged{Delta: phase.ScoreDelta, Score: phase.State.Score}
+	case FallPhase:
+		return CandiesFell{Moves: phase.Moves, State: phase.State}
+	case ShufflePhase:
+		return BoardShuffled{Cells: phase.Cells, State: phase.State}
+	default:
+		return BoardRefilled{Cells: phase.Cells, State: phase.State}
+	}
+}
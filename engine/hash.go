@@ -0,0 +1,87 @@
+package engine
+
+// components of a cell with their own Zobrist keys
+const (
+	colorComponent = iota
+	specialComponent
+	hpComponent
+	jellyComponent
+)
+
+// zobrist returns the key of a component value at a position, the zero value has the zero key
+// so that an empty board without jelly hashes to 0
+func zobrist(coord Coord, component int, value int) uint64 {
+	if value == 0 {
+		return 0
+	}
+
+	z := uint64(coord.Y)<<40 | uint64(coord.X)<<24 | uint64(component)<<16 | uint64(value)
+	z = z*0x9e3779b97f4a7c15 + 0x632be59bd9b4e019
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func cellKey(coord Coord, cell Cell) uint64 {
+	return zobrist(coord, colorComponent, int(cell.Color())) ^
+		zobrist(coord, specialComponent, int(cell.Special())) ^
+		zobrist(coord, hpComponent, int((cell>>hpShift)&hpMask))
+}
+
+// Hash returns the Zobrist hash of the cells and the jelly, kept up to date by SetCell and SetJelly
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+// rehash computes the hash of a board whose cells were not all written by SetCell
+func (b *Board) rehash() {
+	b.hash = 0
+
+	for i := 0; i < b.Height; i++ {
+		for j := 0; j < b.Width; j++ {
+			c := Coord{X: j, Y: i}
+			b.hash ^= cellKey(c, b.GetCell(c)) ^ zobrist(c, jellyComponent, b.GetJelly(c))
+		}
+	}
+}
+
+// Hash returns a hash of the whole state: the board, the random position and the counters.
+// Equal states have the same hash.
+func (s *State) Hash() uint64 {
+	h := s.Board.Hash()
+
+	counters := []uint64{uint64(s.Score), uint64(s.BonusSeconds), s.Rand.Seed, s.Rand.Position, uint64(s.Moves), uint64(s.Ingredients)}
+	for _, cleared := range s.Cleared {
+		counters = append(counters, uint64(cleared))
+	}
+
+	for i, counter := range counters {
+		random := Random{Seed: h, Position: counter + uint64(i)<<56}
+		h = random.Uint64()
+	}
+
+	return h
+}
+
+// Equal tells whether two states have the same board, jelly included, random position and counters
+func (s *State) Equal(other State) bool {
+	if s.Width() != other.Width() || s.Height() != other.Height() {
+		return false
+	}
+
+	if s.Score != other.Score || s.BonusSeconds != other.BonusSeconds || s.Rand != other.Rand ||
+		s.Moves != other.Moves || s.Cleared != other.Cleared || s.Ingredients != other.Ingredients {
+		return false
+	}
+
+	for i := 0; i < s.Height(); i++ {
+		for j := 0; j < s.Width(); j++ {
+			c := Coord{X: j, Y: i}
+			if s.GetCell(c) != other.GetCell(c) || s.Board.GetJelly(c) != other.Board.GetJelly(c) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
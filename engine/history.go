@@ -0,0 +1,117 @@
+package engine
+
+// the number of turns kept for undo when HistoryLimit is not set
+const DefaultHistoryLimit = 50
+
+// Turn is a committed swap with the state right before it, random position included,
+// so that playing the action again from Before gives the same refills
+type Turn struct {
+	Before State
+	Action Action
+}
+
+// History is a bounded list of turns: the turns before position can be undone, the ones after can be redone
+type History struct {
+	Turns    []Turn
+	Position int
+}
+
+func (h *History) push(turn Turn, limit int) {
+	// a new turn forgets the undone ones
+	h.Turns = append(h.Turns[:h.Position], turn)
+
+	if len(h.Turns) > limit {
+		h.Turns = h.Turns[len(h.Turns)-limit:]
+	}
+
+	h.Position = len(h.Turns)
+}
+
+func (h *History) undo() (Turn, bool) {
+	if h.Position == 0 {
+		return Turn{}, false
+	}
+
+	h.Position--
+	return h.Turns[h.Position], true
+}
+
+func (h *History) redo() (Turn, bool) {
+	if h.Position == len(h.Turns) {
+		return Turn{}, false
+	}
+
+	h.Position++
+	return h.Turns[h.Position-1], true
+}
+
+func (e *Engine) historyLimit() int {
+	if e.HistoryLimit <= 0 {
+		return DefaultHistoryLimit
+	}
+	return e.HistoryLimit
+}
+
+// Undo restores the state before the last committed swap
+func (e *Engine) Undo() error {
+	e.mutex.Lock()
+
+	if e.resolving {
+		e.mutex.Unlock()
+		return ErrBusy
+	}
+
+	if e.timeUp {
+		e.mutex.Unlock()
+		return ErrGameOver
+	}
+
+	turn, ok := e.history.undo()
+	if !ok {
+		e.mutex.Unlock()
+		return ErrNothingToUndo
+	}
+
+	e.commit(turn.Before)
+	e.gameOverPublished = false
+	state := e.state.clone()
+	e.mutex.Unlock()
+
+	e.Events.Publish(TurnUndone{Action: turn.Action, State: state})
+
+	e.Tick()
+
+	return nil
+}
+
+// Redo plays the last undone swap again, with the same refills
+func (e *Engine) Redo() error {
+	e.mutex.Lock()
+
+	if e.resolving {
+		e.mutex.Unlock()
+		return ErrBusy
+	}
+
+	if e.timeUp {
+		e.mutex.Unlock()
+		return ErrGameOver
+	}
+
+	turn, ok := e.history.redo()
+	if !ok {
+		e.mutex.Unlock()
+		return ErrNothingToRedo
+	}
+
+	newState, timeline, err := e.Play(turn.Before, turn.Action)
+	if err != nil {
+		e.mutex.Unlock()
+		return err
+	}
+
+	e.commit(newState)
+	e.publishTurn(turn.Action, newState, timeline)
+
+	return nil
+}
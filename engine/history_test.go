@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	e := &Engine{Seed: 1}
+	e.InitRandom()
+
+	states := []State{e.Snapshot()}
+
+	for i := 0; i < 5; i++ {
+		move, ok := e.FindHint(e.Snapshot())
+		if !ok {
+			t.Fatal("no valid move")
+		}
+		if err := e.PlayTurn(move); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, e.Snapshot())
+	}
+
+	for i := len(states) - 2; i >= 2; i-- {
+		if err := e.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		if state := e.Snapshot(); !state.Equal(states[i]) {
+			t.Fatalf("undo restored\n%v\nexpected the state after turn %d\n%v", state, i, states[i])
+		}
+	}
+
+	// the same swaps give the same refills
+	for i := 3; i < len(states); i++ {
+		if err := e.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		if state := e.Snapshot(); !state.Equal(states[i]) {
+			t.Fatalf("redo gave\n%v\nexpected the state after turn %d\n%v", state, i, states[i])
+		}
+	}
+
+	if err := e.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Redo past the last turn returned %v, expected ErrNothingToRedo", err)
+	}
+
+	// a new turn forgets the undone ones
+	if err := e.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	move, _ := e.FindHint(e.Snapshot())
+	if err := e.PlayTurn(move); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Redo after a new turn returned %v, expected ErrNothingToRedo", err)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	e := &Engine{Seed: 1, HistoryLimit: 2}
+	e.InitRandom()
+
+	for i := 0; i < 4; i++ {
+		move, _ := e.FindHint(e.Snapshot())
+		if err := e.PlayTurn(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := e.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("a third undo returned %v, expected ErrNothingToUndo", err)
+	}
+
+	if moves := e.Snapshot().Moves; moves != 2 {
+		t.Errorf("the oldest turn kept leaves %d moves played, expected 2", moves)
+	}
+}
@@ -0,0 +1,401 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// LevelVersion is the version of the level format read by ParseLevel
+const LevelVersion = 1
+
+// MaxLevelSize is the largest width or height of a level
+const MaxLevelSize = 32
+
+// attempts to generate a board with enough valid moves
+const generateAttempts = 100
+
+var ErrInvalidLevel = errors.New("invalid level")
+
+/**
+ * Level is the JSON definition of a level. The rows of the void, blockers and jelly
+ * layers have one character per cell, the board rows use the text notation of ParseBoard:
+ * - void: _ for a hole, . for a playable cell
+ * - blockers: . for none, 1 to 9 for the hit points of a blocker
+ * - jelly: . for none, 1 to 9 for the layers of jelly
+ * - board: the empty cells (.) are generated with the allowed colors, without any match
+ */
+type Level struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// moves (the default), timed or zen
+	Mode   string   `json:"mode,omitempty"`
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Void   []string `json:"void,omitempty"`
+	Board  []string `json:"board,omitempty"`
+	// color letters, all colors when empty
+	Colors []string `json:"colors,omitempty"`
+	Moves  int      `json:"moves,omitempty"`
+	// countdown of a timed level, in seconds
+	TimeLimit  int              `json:"time_limit,omitempty"`
+	Objectives []LevelObjective `json:"objectives,omitempty"`
+	Stars      []int            `json:"stars,omitempty"`
+	Blockers   []string         `json:"blockers,omitempty"`
+	Jelly      []string         `json:"jelly,omitempty"`
+	Refill     LevelRefill      `json:"refill"`
+	// fixed seed for a deterministic refill, random when 0
+	Seed uint64 `json:"seed,omitempty"`
+	// the generated board has at least this many valid moves
+	MinMoves int `json:"min_moves,omitempty"`
+}
+
+type LevelObjective struct {
+	// score, jelly, collect or ingredients
+	Type   string `json:"type"`
+	Target int    `json:"target,omitempty"`
+	Color  string `json:"color,omitempty"`
+}
+
+type LevelRefill struct {
+	BlockerChance    float64 `json:"blocker_chance,omitempty"`
+	TimeBonusChance  float64 `json:"time_bonus_chance,omitempty"`
+	IngredientChance float64 `json:"ingredient_chance,omitempty"`
+}
+
+// LoadLevel reads and validates a level file, then returns an engine ready to play it
+func LoadLevel(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := ParseLevel(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return level.NewEngine()
+}
+
+func ParseLevel(data []byte) (*Level, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var level Level
+	if err := decoder.Decode(&level); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLevel, err)
+	}
+
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &level, nil
+}
+
+func (l *Level) Validate() error {
+	rules, err := l.rules()
+	if err != nil {
+		return err
+	}
+
+	state, err := l.initialState()
+	if err != nil {
+		return err
+	}
+
+	e := Engine{Rules: rules}
+	if e.ingredientsToSpawn(state) > 0 && rules.Refill.IngredientChance == 0 {
+		return l.invalid("the ingredients objective needs ingredients on the board or an ingredient chance")
+	}
+
+	return nil
+}
+
+func (l *Level) invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidLevel, fmt.Sprintf(format, args...))
+}
+
+func (l *Level) mode() (GameMode, error) {
+	if l.Mode == "" {
+		return MoveLimited, nil
+	}
+
+	mode, ok := ParseGameMode(l.Mode)
+	if !ok {
+		return mode, l.invalid("unknown mode %q", l.Mode)
+	}
+	return mode, nil
+}
+
+func (l *Level) rules() (Rules, error) {
+	var rules Rules
+
+	if l.Version != LevelVersion {
+		return rules, l.invalid("unsupported version %d, expected %d", l.Version, LevelVersion)
+	}
+
+	if l.Width < 3 || l.Height < 3 || l.Width > MaxLevelSize || l.Height > MaxLevelSize {
+		return rules, l.invalid("size %dx%d, expected 3 to %d", l.Width, l.Height, MaxLevelSize)
+	}
+
+	mode, err := l.mode()
+	if err != nil {
+		return rules, err
+	}
+
+	if mode == MoveLimited && l.Moves <= 0 {
+		return rules, l.invalid("a moves level needs a positive move limit")
+	}
+
+	if mode == Timed && l.TimeLimit <= 0 {
+		return rules, l.invalid("a timed level needs a positive time limit")
+	}
+
+	if l.Moves < 0 || l.MinMoves < 0 {
+		return rules, l.invalid("negative moves")
+	}
+
+	rules.MoveLimit = l.Moves
+
+	for _, letter := range l.Colors {
+		color, ok := Empty, len(letter) == 1
+		if ok {
+			color, ok = letterColor(letter[0])
+		}
+		if !ok || !color.IsCandy() {
+			return rules, l.invalid("unknown color %q", letter)
+		}
+		rules.Colors = append(rules.Colors, color)
+	}
+
+	if len(l.Colors) > 0 && len(rules.Colors) < 3 {
+		return rules, l.invalid("at least 3 colors are needed")
+	}
+
+	for i, stars := range l.Stars {
+		if stars <= 0 || (i > 0 && stars <= l.Stars[i-1]) {
+			return rules, l.invalid("star thresholds must be positive and increasing")
+		}
+	}
+	rules.Stars = l.Stars
+
+	for _, chance := range []float64{l.Refill.BlockerChance, l.Refill.TimeBonusChance, l.Refill.IngredientChance} {
+		if chance < 0 || chance > 1 {
+			return rules, l.invalid("refill chance %v out of [0, 1]", chance)
+		}
+	}
+
+	rules.Refill = Refill{
+		BlockerChance:    l.Refill.BlockerChance,
+		TimeBonusChance:  l.Refill.TimeBonusChance,
+		IngredientChance: l.Refill.IngredientChance,
+	}
+
+	for _, o := range l.Objectives {
+		objective, err := l.objective(o)
+		if err != nil {
+			return rules, err
+		}
+		rules.Objectives = append(rules.Objectives, objective)
+	}
+
+	return rules, nil
+}
+
+func (l *Level) objective(o LevelObjective) (Objective, error) {
+	kind, ok := parseObjectiveKind(o.Type)
+	if !ok {
+		return Objective{}, l.invalid("unknown objective %q", o.Type)
+	}
+
+	objective := Objective{Kind: kind, Target: o.Target}
+
+	switch kind {
+	case JellyObjective:
+		if len(l.Jelly) == 0 {
+			return objective, l.invalid("a jelly objective needs a jelly layer")
+		}
+	case CollectObjective:
+		color, ok := Empty, len(o.Color) == 1
+		if ok {
+			color, ok = letterColor(o.Color[0])
+		}
+		if !ok || !color.IsCandy() {
+			return objective, l.invalid("unknown color %q to collect", o.Color)
+		}
+		objective.Color = color
+	}
+
+	if kind != JellyObjective && o.Target <= 0 {
+		return objective, l.invalid("objective %s needs a positive target", o.Type)
+	}
+
+	return objective, nil
+}
+
+// initialState builds the board of the level, before the empty cells are generated
+func (l *Level) initialState() (State, error) {
+	state := newState(l.Width, l.Height)
+
+	if len(l.Board) > 0 {
+		board, err := ParseBoard(strings.Join(l.Board, "\n"))
+		if err != nil {
+			return state, fmt.Errorf("%w: board: %v", ErrInvalidLevel, err)
+		}
+
+		if board.Width() != l.Width || board.Height() != l.Height {
+			return state, l.invalid("board is %dx%d, expected %dx%d", board.Width(), board.Height(), l.Width, l.Height)
+		}
+
+		// the parsed board comes with its hash
+		state.Board = board.Board
+	}
+
+	// the void and blockers layers can only be put on the empty cells of the board
+	place := func(c Coord, cell Cell) error {
+		if state.GetCell(c) != Empty && state.GetCell(c) != cell {
+			return l.invalid("%v is both %v and %v", c, state.GetCell(c), cell)
+		}
+		state.SetCell(c, cell)
+		return nil
+	}
+
+	err := l.readLayer("void", l.Void, func(c Coord, char byte) error {
+		switch char {
+		case '_':
+			return place(c, Void)
+		case '.':
+			return nil
+		default:
+			return errUnknownCell
+		}
+	})
+	if err != nil {
+		return state, err
+	}
+
+	err = l.readLayer("blockers", l.Blockers, func(c Coord, char byte) error {
+		switch {
+		case char >= '1' && char <= '9':
+			return place(c, NewBlocker(int(char-'0')))
+		case char == '.':
+			return nil
+		default:
+			return errUnknownCell
+		}
+	})
+	if err != nil {
+		return state, err
+	}
+
+	err = l.readLayer("jelly", l.Jelly, func(c Coord, char byte) error {
+		switch {
+		case char >= '1' && char <= '9':
+			state.Board.SetJelly(c, int(char-'0'))
+			return nil
+		case char == '.':
+			return nil
+		default:
+			return errUnknownCell
+		}
+	})
+	if err != nil {
+		return state, err
+	}
+
+	for i := 0; i < l.Height; i++ {
+		for j := 0; j < l.Width; j++ {
+			c := Coord{X: j, Y: i}
+			if state.GetCell(c) == Void && state.Board.GetJelly(c) > 0 {
+				return state, l.invalid("jelly on the void cell %v", c)
+			}
+		}
+	}
+
+	return state, nil
+}
+
+var errUnknownCell = errors.New("unknown cell")
+
+// readLayer calls read for each cell of a layer
+func (l *Level) readLayer(name string, rows []string, read func(c Coord, char byte) error) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if len(rows) != l.Height {
+		return l.invalid("%s has %d rows, expected %d", name, len(rows), l.Height)
+	}
+
+	for i, row := range rows {
+		row = strings.ReplaceAll(row, " ", "")
+		if len(row) != l.Width {
+			return l.invalid("%s row %d has %d cells, expected %d", name, i+1, len(row), l.Width)
+		}
+
+		for j := 0; j < len(row); j++ {
+			err := read(Coord{X: j, Y: i}, row[j])
+			if errors.Is(err, errUnknownCell) {
+				return l.invalid("%s row %d: unknown cell %q", name, i+1, row[j])
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewEngine returns an engine with the rules and the initial board of the level
+func (l *Level) NewEngine() (*Engine, error) {
+	rules, err := l.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	mode, _ := l.mode()
+
+	e := &Engine{
+		Mode:      mode,
+		Rules:     rules,
+		Seed:      l.Seed,
+		TimeLimit: time.Duration(l.TimeLimit) * time.Second,
+	}
+
+	if e.Seed == 0 {
+		e.Seed = e.seed()
+	}
+
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		state, err := l.initialState()
+		if err != nil {
+			return nil, err
+		}
+
+		e.initBoard(state)
+
+		if len(e.FindValidMoves(e.state)) >= l.MinMoves {
+			break
+		}
+
+		// try another board
+		e.Seed++
+	}
+
+	if len(e.FindValidMoves(e.state)) < l.MinMoves {
+		return nil, l.invalid("no board with %d valid moves could be generated", l.MinMoves)
+	}
+
+	if e.Mode == Timed {
+		e.startedAt = e.now()
+	}
+
+	return e, nil
+}
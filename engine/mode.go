@@ -0,0 +1,44 @@
+package engine
+
+type GameMode int
+
+const (
+	// Classic plays by the rules as they are given, it is the mode of the zero Engine
+	Classic GameMode = iota
+	// Zen has no limit, difficulty ramps up as the score grows
+	Zen
+	// Timed ends the game when the countdown reaches zero
+	Timed
+	// MoveLimited ends the game when the moves are used up or the objectives reached
+	MoveLimited
+)
+
+func (m GameMode) String() string {
+	switch m {
+	case Classic:
+		return "classic"
+	case Zen:
+		return "zen"
+	case Timed:
+		return "timed"
+	case MoveLimited:
+		return "moves"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseGameMode(s string) (GameMode, bool) {
+	switch s {
+	case "classic":
+		return Classic, true
+	case "zen":
+		return Zen, true
+	case "timed":
+		return Timed, true
+	case "moves":
+		return MoveLimited, true
+	default:
+		return Classic, false
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import "testing"
+
+// blockersAfter plays greedy turns and returns the number of blockers then on the board
+func blockersAfter(t *testing.T, e *Engine, turns int) int {
+	t.Helper()
+
+	for i := 0; i < turns; i++ {
+		move, ok := e.FindHint(e.Snapshot())
+		if !ok {
+			break
+		}
+		if err := e.PlayTurn(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := e.Snapshot()
+
+	blockers := 0
+	for _, cell := range state.Board.Cells {
+		if cell.Color() == Blocker {
+			blockers++
+		}
+	}
+	return blockers
+}
+
+func TestZeroEngineIsNotZen(t *testing.T) {
+	e := &Engine{Seed: 1, Rules: Rules{Refill: Refill{BlockerChance: 0.5}}}
+	e.InitRandom()
+
+	if e.Mode != Classic {
+		t.Errorf("the zero engine is in %v mode", e.Mode)
+	}
+
+	if colors := e.colors(0); len(colors) != len(AllColors) {
+		t.Errorf("the zero engine plays with %d colors, expected %d", len(colors), len(AllColors))
+	}
+
+	if blockersAfter(t, e, 10) == 0 {
+		t.Error("no blocker was refilled with a blocker chance of 0.5")
+	}
+}
+
+func TestZenHonorsRefill(t *testing.T) {
+	e := &Engine{Seed: 1, Rules: Rules{Refill: Refill{BlockerChance: 0.5}}}
+	e.InitZen()
+
+	if colors := e.colors(0); len(colors) != ZenProgression[0].Colors {
+		t.Errorf("zen starts with %d colors, expected %d", len(colors), ZenProgression[0].Colors)
+	}
+
+	if blockersAfter(t, e, 10) == 0 {
+		t.Error("zen ignored the blocker chance of the rules")
+	}
+}
@@ -0,0 +1,71 @@
+package engine
+
+// MoveCache remembers the valid moves of a board, so that ValidMoves only checks again the swaps near the cells changed since
+type MoveCache struct {
+	base  uint64
+	moves []Action
+	ok    bool
+}
+
+// ValidMoves returns the moves of FindValidMoves, in the same order. When the cache holds the moves of the board
+// the changes of the state started from, only the swaps near the changed cells are checked again.
+// The returned slice is shared with the cache and must not be modified.
+func (e *Engine) ValidMoves(state *State, cache *MoveCache) []Action {
+	board := &state.Board
+
+	if cache.ok && board.moveDirty.tracked && board.movesBase == cache.base {
+		// a swap depends on the cells up to 2 cells away from both of its ends
+		if from, to, ok := board.moveDirty.bounds(board, 3); ok {
+			cache.moves = e.mergeMoves(*state, cache.moves, from, to)
+		}
+	} else {
+		cache.moves = e.FindValidMoves(*state)
+	}
+
+	board.moveDirty.reset()
+	board.movesBase = board.hash
+	cache.base = board.hash
+	cache.ok = true
+
+	return cache.moves
+}
+
+// mergeMoves replaces the moves starting from the box with the ones valid now, keeping the order of FindValidMoves
+func (e *Engine) mergeMoves(state State, moves []Action, from, to Coord) []Action {
+	fresh := e.validMovesIn(state, from, to, nil)
+	merged := make([]Action, 0, len(moves)+len(fresh))
+
+	inBox := func(c Coord) bool {
+		return c.X >= from.X && c.X <= to.X && c.Y >= from.Y && c.Y <= to.Y
+	}
+
+	before := func(a, b Action) bool {
+		return a.From.Y < b.From.Y || (a.From.Y == b.From.Y && (a.From.X < b.From.X ||
+			(a.From.X == b.From.X && moveDirection(a) < moveDirection(b))))
+	}
+
+	for _, move := range moves {
+		if inBox(move.From) {
+			continue
+		}
+
+		for len(fresh) > 0 && before(fresh[0], move) {
+			merged = append(merged, fresh[0])
+			fresh = fresh[1:]
+		}
+
+		merged = append(merged, move)
+	}
+
+	return append(merged, fresh...)
+}
+
+// moveDirection returns the direction from the first cell of the action to the second one, -1 when they are not adjacent
+func moveDirection(action Action) Direction {
+	for dir := Up; dir <= Right; dir++ {
+		if GetNeighbor(dir, action.From) == action.To {
+			return dir
+		}
+	}
+	return -1
+}
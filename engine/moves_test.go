@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func newTestEngine(seed uint64, width, height int) *Engine {
+	e := &Engine{Seed: seed, Rules: Rules{Refill: Refill{BlockerChance: 0.02}}}
+	e.initBoard(newState(width, height))
+	return e
+}
+
+func TestValidMovesMatchFullScan(t *testing.T) {
+	for seed := uint64(1); seed <= 20; seed++ {
+		size := 9
+		if seed%4 == 0 {
+			size = MaxLevelSize
+		}
+
+		e := newTestEngine(seed, size, size)
+		state := e.Snapshot()
+		random := NewRandom(seed)
+
+		var cache MoveCache
+		inPlace := seed%2 == 0
+
+		for turn := 0; turn < 40; turn++ {
+			moves := e.ValidMoves(&state, &cache)
+
+			if expected := e.FindValidMoves(state); !slices.Equal(moves, expected) {
+				t.Fatalf("seed %d, turn %d: ValidMoves\n%v\nFindValidMoves\n%v\non\n%v", seed, turn, moves, expected, state)
+			}
+
+			if len(moves) == 0 {
+				break
+			}
+
+			move := moves[random.Intn(len(moves))]
+
+			// both ways of playing must keep the cache usable
+			var err error
+			if inPlace {
+				err = e.PlayInPlace(&state, move)
+			} else {
+				state, _, err = e.Play(state, move)
+			}
+			if err != nil {
+				t.Fatalf("seed %d, turn %d: %v", seed, turn, err)
+			}
+		}
+	}
+}
+
+// benchmarkRollout plays random moves on a large board, finding the moves after each one with findMoves
+func benchmarkRollout(b *testing.B, findMoves func(e *Engine, state *State, cache *MoveCache) []Action) {
+	e := newTestEngine(1, MaxLevelSize, MaxLevelSize)
+	start := e.Snapshot()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var state State
+	for i := 0; i < b.N; i++ {
+		state.CopyFrom(start)
+		random := NewRandom(uint64(i))
+		var cache MoveCache
+
+		for turn := 0; turn < 20; turn++ {
+			moves := findMoves(e, &state, &cache)
+			if len(moves) == 0 {
+				break
+			}
+
+			if err := e.PlayInPlace(&state, moves[random.Intn(len(moves))]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkRolloutFindValidMoves(b *testing.B) {
+	benchmarkRollout(b, func(e *Engine, state *State, _ *MoveCache) []Action {
+		return e.FindValidMoves(*state)
+	})
+}
+
+func BenchmarkRolloutValidMoves(b *testing.B) {
+	benchmarkRollout(b, func(e *Engine, state *State, cache *MoveCache) []Action {
+		return e.ValidMoves(state, cache)
+	})
+}
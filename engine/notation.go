@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Move notation: the column letters and the row number of the swapped cell, then the direction of the swap.
+ * Columns go a, b, ..., z, aa, ab, ... from the left. Unlike chess, rows are numbered from 1 at the top,
+ * in the order of Coord.Y and of the lines of the text board notation:
+ * e4> swaps e4 with f4, c7^ swaps c7 with c6. A swap between cells that are not adjacent is written e4-g6.
+ * The notation covers the largest level, MaxLevelSize cells wide and high: a cell beyond it cannot be written or read.
+ */
+
+var ErrInvalidNotation = errors.New("invalid move notation")
+
+var directionSymbols = map[Direction]byte{
+	Up:    '^',
+	Down:  'v',
+	Left:  '<',
+	Right: '>',
+}
+
+func (d Direction) String() string {
+	if symbol, ok := directionSymbols[d]; ok {
+		return string(symbol)
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
+// String returns the column letters and the row number of the coordinate,
+// or (x,y) when it is out of the notation, which ParseAction rejects
+func (c Coord) String() string {
+	if !inNotation(c) {
+		return fmt.Sprintf("(%d,%d)", c.X, c.Y)
+	}
+
+	var letters []byte
+	for x := c.X + 1; x > 0; x = (x - 1) / 26 {
+		letters = append([]byte{byte('a' + (x-1)%26)}, letters...)
+	}
+
+	return fmt.Sprintf("%s%d", letters, c.Y+1)
+}
+
+func (a Action) String() string {
+	if dir := moveDirection(a); dir >= 0 {
+		return a.From.String() + dir.String()
+	}
+
+	return a.From.String() + "-" + a.To.String()
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	action, err := ParseAction(string(text))
+	if err != nil {
+		return err
+	}
+
+	*a = action
+	return nil
+}
+
+// ParseAction reads a move written by Action.String
+func ParseAction(text string) (Action, error) {
+	text = strings.TrimSpace(text)
+
+	if from, to, ok := strings.Cut(text, "-"); ok {
+		fromCoord, err := parseCoord(from)
+		if err != nil {
+			return Action{}, fmt.Errorf("%w: %q: %v", ErrInvalidNotation, text, err)
+		}
+
+		toCoord, err := parseCoord(to)
+		if err != nil {
+			return Action{}, fmt.Errorf("%w: %q: %v", ErrInvalidNotation, text, err)
+		}
+
+		return Action{From: fromCoord, To: toCoord}, nil
+	}
+
+	if text == "" {
+		return Action{}, fmt.Errorf("%w: empty move", ErrInvalidNotation)
+	}
+
+	dir, ok := symbolDirection(text[len(text)-1])
+	if !ok {
+		return Action{}, fmt.Errorf("%w: %q: missing direction, expected one of ^ v < >", ErrInvalidNotation, text)
+	}
+
+	from, err := parseCoord(text[:len(text)-1])
+	if err != nil {
+		return Action{}, fmt.Errorf("%w: %q: %v", ErrInvalidNotation, text, err)
+	}
+
+	to := GetNeighbor(dir, from)
+	if !inNotation(to) {
+		return Action{}, fmt.Errorf("%w: %q: the swap leaves the board", ErrInvalidNotation, text)
+	}
+
+	return Action{From: from, To: to}, nil
+}
+
+// inNotation tells whether a cell can be on the largest board
+func inNotation(c Coord) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < MaxLevelSize && c.Y < MaxLevelSize
+}
+
+func parseCoord(text string) (Coord, error) {
+	i := 0
+	x := 0
+	for ; i < len(text) && text[i] >= 'a' && text[i] <= 'z'; i++ {
+		x = x*26 + int(text[i]-'a') + 1
+		if x > MaxLevelSize {
+			return Coord{}, fmt.Errorf("column %q beyond the largest board", text[:i+1])
+		}
+	}
+
+	if i == 0 {
+		return Coord{}, fmt.Errorf("missing column letter in %q", text)
+	}
+
+	// a sign or a leading zero would not be written back the same
+	row, err := strconv.Atoi(text[i:])
+	if err != nil || row < 1 || text[i] == '+' || text[i] == '0' {
+		return Coord{}, fmt.Errorf("invalid row in %q", text)
+	}
+
+	if row > MaxLevelSize {
+		return Coord{}, fmt.Errorf("row %d beyond the largest board", row)
+	}
+
+	return Coord{X: x - 1, Y: row - 1}, nil
+}
+
+func symbolDirection(symbol byte) (Direction, bool) {
+	for dir, s := range directionSymbols {
+		if s == symbol {
+			return dir, true
+		}
+	}
+	return 0, false
+}
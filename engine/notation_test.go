@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestActionNotation(t *testing.T) {
+	for text, expected := range map[string]Action{
+		"a1>":   {From: Coord{X: 0, Y: 0}, To: Coord{X: 1, Y: 0}},
+		"e4>":   {From: Coord{X: 4, Y: 3}, To: Coord{X: 5, Y: 3}},
+		"c7^":   {From: Coord{X: 2, Y: 6}, To: Coord{X: 2, Y: 5}},
+		"b2v":   {From: Coord{X: 1, Y: 1}, To: Coord{X: 1, Y: 2}},
+		"af32<": {From: Coord{X: 31, Y: 31}, To: Coord{X: 30, Y: 31}},
+		"e4-g6": {From: Coord{X: 4, Y: 3}, To: Coord{X: 6, Y: 5}},
+	} {
+		action, err := ParseAction(text)
+		if err != nil {
+			t.Errorf("ParseAction(%q): %v", text, err)
+			continue
+		}
+		if action != expected {
+			t.Errorf("ParseAction(%q) = %+v, expected %+v", text, action, expected)
+		}
+		if action.String() != text {
+			t.Errorf("%+v is written %q, expected %q", action, action.String(), text)
+		}
+	}
+}
+
+func TestActionNotationRoundTrip(t *testing.T) {
+	for y := 0; y < MaxLevelSize; y++ {
+		for x := 0; x < MaxLevelSize; x++ {
+			c := Coord{X: x, Y: y}
+
+			for _, dir := range []Direction{Up, Down, Left, Right} {
+				action := Action{From: c, To: GetNeighbor(dir, c)}
+				if !inNotation(action.To) {
+					continue
+				}
+
+				parsed, err := ParseAction(action.String())
+				if err != nil || parsed != action {
+					t.Fatalf("%+v is written %q and read back as %+v, %v", action, action.String(), parsed, err)
+				}
+			}
+		}
+	}
+}
+
+func TestActionNotationRejectsCellsOffTheBoard(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"e4",
+		"4>",
+		"E4>",
+		"e0>",
+		"e04>",
+		"e+4>",
+		"e-4>",
+		"a1^",
+		"a1<",
+		"af1>",
+		"a32v",
+		"ag1<",
+		"a33^",
+		"(-1,0)>",
+		"a1-(-1,0)",
+	} {
+		if action, err := ParseAction(text); !errors.Is(err, ErrInvalidNotation) {
+			t.Errorf("ParseAction(%q) = %+v, %v, expected ErrInvalidNotation", text, action, err)
+		}
+	}
+
+	off := Action{From: Coord{X: 0, Y: 0}, To: Coord{X: 0, Y: -1}}
+	if _, err := ParseAction(off.String()); err == nil {
+		t.Errorf("%q, written from a swap leaving the board, is read back", off.String())
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+type ObjectiveKind int
+
+const (
+	// reach a score
+	ScoreObjective ObjectiveKind = iota
+	// clear every layer of jelly
+	JellyObjective
+	// explode a number of candies of a color
+	CollectObjective
+	// bring ingredients down to the bottom of the board
+	IngredientObjective
+)
+
+func (k ObjectiveKind) String() string {
+	switch k {
+	case ScoreObjective:
+		return "score"
+	case JellyObjective:
+		return "jelly"
+	case CollectObjective:
+		return "collect"
+	case IngredientObjective:
+		return "ingredients"
+	default:
+		return "unknown"
+	}
+}
+
+func parseObjectiveKind(s string) (ObjectiveKind, bool) {
+	for _, kind := range []ObjectiveKind{ScoreObjective, JellyObjective, CollectObjective, IngredientObjective} {
+		if kind.String() == s {
+			return kind, true
+		}
+	}
+	return ScoreObjective, false
+}
+
+type Objective struct {
+	Kind   ObjectiveKind
+	Target int
+	// color to collect, for CollectObjective
+	Color Cell
+}
+
+// Remaining returns how much is left to do for the objective, 0 once it is reached
+func (o Objective) Remaining(state State) int {
+	var remaining int
+
+	switch o.Kind {
+	case ScoreObjective:
+		remaining = o.Target - state.Score
+	case JellyObjective:
+		remaining = state.Board.JellyLeft()
+	case CollectObjective:
+		remaining = o.Target - state.Cleared[o.Color]
+	case IngredientObjective:
+		remaining = o.Target - state.Ingredients
+	}
+
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// IsWon tells whether the rules have objectives and all of them are reached
+func (e *Engine) IsWon(state State) bool {
+	if len(e.Rules.Objectives) == 0 {
+		return false
+	}
+
+	for _, objective := range e.Rules.Objectives {
+		if objective.Remaining(state) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EndsOnWin tells whether reaching the objectives ends the game. A score objective is only the first star:
+// the game goes on for the next ones until the moves or the time run out.
+func (e *Engine) EndsOnWin() bool {
+	if e.Rules.MoveLimit <= 0 && e.Mode != Timed {
+		return true
+	}
+
+	for _, objective := range e.Rules.Objectives {
+		if objective.Kind == ScoreObjective {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ingredientsToSpawn returns how many ingredients are still needed, on top of the ones on the board
+func (e *Engine) ingredientsToSpawn(state State) int {
+	needed := 0
+	for _, objective := range e.Rules.Objectives {
+		if objective.Kind == IngredientObjective {
+			needed += objective.Target
+		}
+	}
+
+	needed -= state.Ingredients
+
+	for i := 0; i < state.Height(); i++ {
+		for j := 0; j < state.Width(); j++ {
+			if state.GetCell(Coord{X: j, Y: i}) == Ingredient {
+				needed--
+			}
+		}
+	}
+
+	return needed
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEndsOnWin(t *testing.T) {
+	score := Objective{Kind: ScoreObjective, Target: 60}
+	jelly := Objective{Kind: JellyObjective}
+
+	for _, test := range []struct {
+		name     string
+		engine   *Engine
+		expected bool
+	}{
+		{"score with a move limit", &Engine{Rules: Rules{MoveLimit: 15, Objectives: []Objective{score}}}, false},
+		{"score and jelly with a move limit", &Engine{Rules: Rules{MoveLimit: 15, Objectives: []Objective{jelly, score}}}, false},
+		{"timed score", &Engine{Mode: Timed, Rules: Rules{Objectives: []Objective{score}}}, false},
+		{"score without any limit", &Engine{Rules: Rules{Objectives: []Objective{score}}}, true},
+		{"jelly with a move limit", &Engine{Rules: Rules{MoveLimit: 15, Objectives: []Objective{jelly}}}, true},
+	} {
+		if got := test.engine.EndsOnWin(); got != test.expected {
+			t.Errorf("%s: EndsOnWin() = %v, expected %v", test.name, got, test.expected)
+		}
+	}
+}
+
+// the stars above the score objective can only be reached by playing on after the win
+func TestScoreLevelPlaysOnAfterWin(t *testing.T) {
+	data, err := os.ReadFile("../levels/packs/classic/01-first-steps.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	level, err := ParseLevel(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	level.Seed = 1
+	e, err := level.NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wonAt := -1
+
+	for !e.IsGameOver() {
+		state := e.Snapshot()
+		if wonAt < 0 && e.IsWon(state) {
+			wonAt = state.Moves
+		}
+
+		// the best scoring move
+		var move Action
+		best := -1
+		for _, action := range e.FindValidMoves(state) {
+			played, _, _ := e.Play(state, action)
+			if played.Score > best {
+				move, best = action, played.Score
+			}
+		}
+
+		if err := e.PlayTurn(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state := e.Snapshot()
+	if e.MovesLeft(state) != 0 {
+		t.Errorf("the game ended with %d moves left", e.MovesLeft(state))
+	}
+	if wonAt < 0 || wonAt == state.Moves {
+		t.Errorf("the objective was reached after %d moves out of %d, expected the game to go on", wonAt, state.Moves)
+	}
+}
@@ -0,0 +1,97 @@
+package engine
+
+// Play applies an action to a state and resolves the whole turn synchronously.
+// It never touches the engine state: the resulting state and the ordered phases of the turn are returned instead.
+func (e *Engine) Play(state State, action Action) (State, Timeline, error) {
+	if err := e.isValidAction(state, action); err != nil {
+		return state, nil, err
+	}
+
+	swapped := state.clone()
+	swapped.SwapCells(action.From, action.To)
+	swapped.Moves++
+
+	timeline := Timeline{{
+		Kind:  SwapPhase,
+		Cells: []Coord{action.From, action.To},
+		State: swapped,
+	}}
+
+	newState := e.resolve(swapped, &timeline)
+
+	return newState, timeline, nil
+}
+
+// PlayInPlace applies an action to the state itself and resolves the whole turn, without recording its phases:
+// the allocation-light variant of Play for searches that play millions of moves
+func (e *Engine) PlayInPlace(state *State, action Action) error {
+	if err := e.isValidAction(*state, action); err != nil {
+		return err
+	}
+
+	state.SwapCells(action.From, action.To)
+	state.Moves++
+
+	e.resolveInPlace(state)
+
+	return nil
+}
+
+// resolve explodes, falls and refills until the board is stable, then shuffles it when no valid move is left.
+// The phases are recorded when timeline is not nil.
+func (e *Engine) resolve(state State, timeline *Timeline) State {
+	if timeline == nil {
+		state = state.clone()
+		e.resolveInPlace(&state)
+		return state
+	}
+
+	for {
+		exploded, cells := e.explode(state)
+		if len(cells) == 0 {
+			if e.hasValidMove(state) {
+				return state
+			}
+
+			shuffled := state.clone()
+			if cells, ok := e.shuffleInPlace(&shuffled); ok {
+				*timeline = append(*timeline, Phase{Kind: ShufflePhase, Cells: cells, State: shuffled})
+				return shuffled
+			}
+
+			return state
+		}
+
+		scored := exploded
+		scored.Score += len(cells)
+
+		fallen, moves := e.fall(scored)
+		refilled, filled := e.refill(fallen)
+
+		*timeline = append(*timeline,
+			Phase{Kind: ExplodePhase, Cells: cells, State: exploded},
+			Phase{Kind: ScorePhase, ScoreDelta: len(cells), State: scored},
+			Phase{Kind: FallPhase, Moves: moves, State: fallen},
+			Phase{Kind: RefillPhase, Cells: filled, State: refilled},
+		)
+
+		state = refilled
+	}
+}
+
+func (e *Engine) resolveInPlace(state *State) {
+	for {
+		count := e.explodeInPlace(state, nil)
+		if count == 0 {
+			if !e.hasValidMove(*state) {
+				e.shuffleInPlace(state)
+			}
+			return
+		}
+
+		state.Score += count
+
+		e.fallInPlace(state, nil)
+		e.refillInPlace(state, nil)
+	}
+}
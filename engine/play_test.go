@@ -0,0 +1,84 @@
+package engine
+
+import "testing"
+
+func TestPlayTimeline(t *testing.T) {
+	e := &Engine{}
+
+	state, err := ParseBoard(`
+		R R G R Y
+		Y B Y B G
+		B Y B Y B
+		G B G B Y
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Rand = NewRandom(1)
+
+	before := state.clone()
+
+	next, timeline, err := e.Play(state, Action{From: Coord{X: 2, Y: 0}, To: Coord{X: 3, Y: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !state.Equal(before) {
+		t.Errorf("Play changed its input state to\n%v", state)
+	}
+
+	if len(timeline) < 5 || timeline[0].Kind != SwapPhase {
+		t.Fatalf("the timeline %v does not start with a swap and a cascade", kinds(timeline))
+	}
+
+	cascade := []PhaseKind{ExplodePhase, ScorePhase, FallPhase, RefillPhase}
+	steps := timeline[1:]
+
+	// a shuffle can only close the turn
+	if last := steps[len(steps)-1]; last.Kind == ShufflePhase {
+		steps = steps[:len(steps)-1]
+	}
+
+	if len(steps)%len(cascade) != 0 {
+		t.Fatalf("the timeline %v is not made of whole cascades", kinds(timeline))
+	}
+
+	score := 0
+	for i, phase := range steps {
+		if phase.Kind != cascade[i%len(cascade)] {
+			t.Fatalf("phase %d of %v is a %v, expected a %v", i+1, kinds(timeline), phase.Kind, cascade[i%len(cascade)])
+		}
+
+		if phase.Kind == ScorePhase {
+			exploded := steps[i-1]
+			if phase.ScoreDelta != len(exploded.Cells) {
+				t.Errorf("phase %d scores %d for %d exploded cells", i+1, phase.ScoreDelta, len(exploded.Cells))
+			}
+
+			score += phase.ScoreDelta
+			if phase.State.Score != score {
+				t.Errorf("phase %d has a score of %d, expected %d", i+1, phase.State.Score, score)
+			}
+		}
+	}
+
+	if first := steps[0]; len(first.Cells) != 3 {
+		t.Errorf("the swap exploded %v, expected the line of three red", first.Cells)
+	}
+
+	if next.Score != score || next.Moves != 1 {
+		t.Errorf("Play returned a score of %d after %d moves, expected %d after 1", next.Score, next.Moves, score)
+	}
+
+	if last := timeline[len(timeline)-1]; !last.State.Equal(next) {
+		t.Error("the last phase is not the returned state")
+	}
+}
+
+func kinds(timeline Timeline) []PhaseKind {
+	var kinds []PhaseKind
+	for _, phase := range timeline {
+		kinds = append(kinds, phase.Kind)
+	}
+	return kinds
+}
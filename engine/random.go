@@ -0,0 +1,31 @@
+package engine
+
+// Random is a counter based generator (splitmix64) stored by value in the state,
+// so that copying a state also copies its position in the random sequence
+type Random struct {
+	Seed     uint64
+	Position uint64
+}
+
+func NewRandom(seed uint64) Random {
+	return Random{Seed: seed}
+}
+
+func (r *Random) Uint64() uint64 {
+	r.Position++
+
+	z := r.Seed + r.Position*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Intn returns a number in [0, n)
+func (r *Random) Intn(n int) int {
+	return int(r.Uint64() % uint64(n))
+}
+
+// Float64 returns a number in [0, 1)
+func (r *Random) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
@@ -0,0 +1,51 @@
+package engine
+
+// region is the bounding box of the cells changed since it was last reset,
+// a region that was never reset covers the whole board
+type region struct {
+	tracked  bool
+	min, max Coord
+}
+
+func (r *region) reset() {
+	r.tracked = true
+	r.min = Coord{X: 1, Y: 1}
+	r.max = Coord{X: 0, Y: 0}
+}
+
+func (r *region) add(c Coord) {
+	if !r.tracked {
+		return
+	}
+
+	if r.min.X > r.max.X {
+		r.min, r.max = c, c
+		return
+	}
+
+	r.min.X = min(r.min.X, c.X)
+	r.min.Y = min(r.min.Y, c.Y)
+	r.max.X = max(r.max.X, c.X)
+	r.max.Y = max(r.max.Y, c.Y)
+}
+
+// isFull tells whether the region still covers the whole board
+func (r *region) isFull() bool {
+	return !r.tracked
+}
+
+// bounds returns the region grown by margin cells on each side and clipped to the board, ok is false when it is empty
+func (r *region) bounds(b *Board, margin int) (from, to Coord, ok bool) {
+	if !r.tracked {
+		return Coord{}, Coord{X: b.Width - 1, Y: b.Height - 1}, b.Width > 0 && b.Height > 0
+	}
+
+	if r.min.X > r.max.X {
+		return Coord{}, Coord{}, false
+	}
+
+	from = Coord{X: max(r.min.X-margin, 0), Y: max(r.min.Y-margin, 0)}
+	to = Coord{X: min(r.max.X+margin, b.Width-1), Y: min(r.max.Y+margin, b.Height-1)}
+
+	return from, to, true
+}
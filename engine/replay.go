@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReplayVersion is the version of the replay format read by ReadReplay
+const ReplayVersion = 4
+
+var (
+	ErrInvalidReplay  = errors.New("invalid replay")
+	ErrReplayMismatch = errors.New("replay does not reproduce the recorded game")
+)
+
+// Replay is a recorded game: its rules, its starting state, random position included, and the actions played since
+type Replay struct {
+	Version   int
+	Mode      GameMode
+	Seed      uint64
+	Rules     Rules
+	TimeLimit time.Duration
+	// time left when the recording started, in a timed game
+	TimeLeft time.Duration
+	Start    State
+	Actions  []ReplayAction
+	// score at the end of the recording
+	Score int
+}
+
+// ReplayAction is an action with the time it was played at, since the start of the recording
+type ReplayAction struct {
+	Action Action
+	At     time.Duration
+}
+
+// Recorder listens to the events of an engine and records the turns played, an undone turn is removed from the recording
+type Recorder struct {
+	engine    *Engine
+	mutex     sync.Mutex
+	replay    Replay
+	startedAt time.Time
+	// time the countdown was paused before the recording started
+	pausedBefore time.Duration
+	listeners    []int
+}
+
+// NewRecorder starts recording the game of an engine from its current state
+func NewRecorder(e *Engine) *Recorder {
+	e.mutex.Lock()
+	r := &Recorder{
+		engine: e,
+		replay: Replay{
+			Version:   ReplayVersion,
+			Mode:      e.Mode,
+			Seed:      e.Seed,
+			Rules:     e.Rules,
+			TimeLimit: e.TimeLimit,
+			TimeLeft:  e.timeLeft(),
+			Start:     e.state.clone(),
+		},
+		startedAt:    e.now(),
+		pausedBefore: e.pausedFor(),
+	}
+	e.mutex.Unlock()
+
+	r.listeners = append(r.listeners,
+		On(&e.Events, func(ev SwapCommitted) {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+
+			r.replay.Actions = append(r.replay.Actions, ReplayAction{Action: ev.Action, At: r.elapsed()})
+		}),
+		On(&e.Events, func(ev TurnUndone) {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+
+			if len(r.replay.Actions) > 0 {
+				r.replay.Actions = r.replay.Actions[:len(r.replay.Actions)-1]
+			}
+		}),
+	)
+
+	return r
+}
+
+// elapsed returns the time played since the start of the recording: a replay runs on a clock that was never paused
+func (r *Recorder) elapsed() time.Duration {
+	r.engine.mutex.Lock()
+	defer r.engine.mutex.Unlock()
+
+	return r.engine.now().Sub(r.startedAt) - (r.engine.pausedFor() - r.pausedBefore)
+}
+
+// Stop stops listening to the engine
+func (r *Recorder) Stop() {
+	for _, id := range r.listeners {
+		r.engine.Events.Unsubscribe(id)
+	}
+	r.listeners = nil
+}
+
+// Replay returns the recording so far, with the current score of the engine
+func (r *Recorder) Replay() Replay {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	replay := r.replay
+	replay.Start = replay.Start.clone()
+	replay.Actions = append([]ReplayAction{}, replay.Actions...)
+	replay.Score = r.engine.Snapshot().Score
+
+	return replay
+}
+
+// WriteFile writes the recording so far to a file
+func (r *Recorder) WriteFile(path string) error {
+	var data bytes.Buffer
+
+	// keep the moves readable: e4> rather than e4\u003e
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(r.Replay()); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data.Bytes(), 0o644)
+}
+
+// Run plays the actions of the replay again, on a clock following their timestamps,
+// and checks that the final score is the recorded one
+func (r Replay) Run() (State, error) {
+	if r.Version != ReplayVersion {
+		return State{}, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidReplay, r.Version, ReplayVersion)
+	}
+
+	clock := NewManualClock(time.Unix(0, 0))
+
+	e, err := restore(Save{
+		Version:   SaveVersion,
+		Mode:      r.Mode,
+		Seed:      r.Seed,
+		Rules:     r.Rules,
+		TimeLimit: r.TimeLimit,
+		TimeLeft:  r.TimeLeft,
+		State:     r.Start,
+	}, clock)
+	if err != nil {
+		return State{}, fmt.Errorf("%w: %v", ErrInvalidReplay, err)
+	}
+
+	var elapsed time.Duration
+
+	for i, played := range r.Actions {
+		if played.At < elapsed {
+			return State{}, fmt.Errorf("%w: action %d is older than the previous one", ErrInvalidReplay, i)
+		}
+
+		clock.Advance(played.At - elapsed)
+		elapsed = played.At
+
+		if err := e.PlayTurn(played.Action); err != nil {
+			return e.Snapshot(), fmt.Errorf("%w: action %d: %v", ErrReplayMismatch, i, err)
+		}
+	}
+
+	state := e.Snapshot()
+
+	if state.Score != r.Score {
+		return state, fmt.Errorf("%w: score %d, expected %d", ErrReplayMismatch, state.Score, r.Score)
+	}
+
+	return state, nil
+}
+
+func ReadReplay(path string) (Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Replay{}, err
+	}
+
+	var replay Replay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		return Replay{}, fmt.Errorf("%w: %s: %v", ErrInvalidReplay, path, err)
+	}
+
+	return replay, nil
+}
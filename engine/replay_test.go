@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// playHint plays the first hint of the engine
+func playHint(t *testing.T, e *Engine) {
+	t.Helper()
+
+	move, ok := e.FindHint(e.Snapshot())
+	if !ok {
+		t.Fatal("no valid move")
+	}
+	if err := e.PlayTurn(move); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runRecording runs the replay of a recorder, through JSON as in a file, and checks its final score
+func runRecording(t *testing.T, e *Engine, r *Recorder) {
+	t.Helper()
+
+	data, err := json.Marshal(r.Replay())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var replay Replay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := replay.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := e.Snapshot(); !state.Equal(expected) {
+		t.Errorf("the replay ends on\n%v\nexpected\n%v", state, expected)
+	}
+}
+
+func TestReplayRun(t *testing.T) {
+	e := &Engine{Seed: 1, Rules: Rules{MoveLimit: 20}}
+	e.InitRandom()
+
+	r := NewRecorder(e)
+	defer r.Stop()
+
+	for i := 0; i < 4; i++ {
+		playHint(t, e)
+	}
+
+	// an undone turn leaves the recording, a redone one comes back
+	for i := 0; i < 2; i++ {
+		if err := e.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Redo(); err != nil {
+		t.Fatal(err)
+	}
+
+	playHint(t, e)
+
+	if actions := len(r.Replay().Actions); actions != 4 {
+		t.Errorf("%d actions recorded, expected 4", actions)
+	}
+
+	runRecording(t, e, r)
+}
+
+func TestReplayRunAfterPause(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	e := &Engine{Seed: 1, Clock: clock}
+	e.InitTimed(10 * time.Second)
+
+	r := NewRecorder(e)
+	defer r.Stop()
+
+	e.Pause()
+	clock.Advance(30 * time.Second)
+	e.Resume()
+
+	clock.Advance(9 * time.Second)
+	playHint(t, e)
+
+	if at := r.Replay().Actions[0].At; at != 9*time.Second {
+		t.Errorf("the action is recorded at %v, expected 9s", at)
+	}
+
+	runRecording(t, e, r)
+}
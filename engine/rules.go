@@ -0,0 +1,101 @@
+package engine
+
+// Refill tells what can appear in the cells refilled after a fall
+type Refill struct {
+	BlockerChance    float64
+	TimeBonusChance  float64
+	IngredientChance float64
+}
+
+// Rules are the per game settings, the zero value plays with every color and no limit
+type Rules struct {
+	// allowed colors, all of them when empty
+	Colors     []Cell
+	MoveLimit  int
+	Objectives []Objective
+	// scores to reach for one, two, three stars
+	Stars  []int
+	Refill Refill
+}
+
+// isValid checks rules read from a file, so that a corrupt one cannot make the engine panic later
+func (r Rules) isValid() bool {
+	isColor := func(c Cell) bool {
+		return c >= Red && c <= Orange
+	}
+
+	for _, color := range r.Colors {
+		if !isColor(color) {
+			return false
+		}
+	}
+
+	if r.MoveLimit < 0 {
+		return false
+	}
+
+	for _, o := range r.Objectives {
+		if o.Kind < ScoreObjective || o.Kind > IngredientObjective || o.Target < 0 {
+			return false
+		}
+		if o.Kind == CollectObjective && !isColor(o.Color) {
+			return false
+		}
+	}
+
+	for _, chance := range []float64{r.Refill.BlockerChance, r.Refill.TimeBonusChance, r.Refill.IngredientChance} {
+		if chance < 0 || chance > 1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *Engine) colors(score int) []Cell {
+	colors := e.Rules.Colors
+	if len(colors) == 0 {
+		colors = AllColors
+	}
+
+	if e.Mode == Zen {
+		count := zenLevel(score).Colors
+		if count < len(colors) {
+			colors = colors[:count]
+		}
+	}
+
+	return colors
+}
+
+// blockerChance is the chance of the rules, raised by the progression in zen mode
+func (e *Engine) blockerChance(score int) float64 {
+	if e.Mode == Zen {
+		return max(e.Rules.Refill.BlockerChance, zenLevel(score).BlockerChance)
+	}
+	return e.Rules.Refill.BlockerChance
+}
+
+// MovesLeft returns the swaps left before the move limit, -1 when there is no limit
+func (e *Engine) MovesLeft(state State) int {
+	if e.Rules.MoveLimit <= 0 {
+		return -1
+	}
+
+	left := e.Rules.MoveLimit - state.Moves
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Stars returns how many star thresholds the score has reached
+func (e *Engine) Stars(state State) int {
+	stars := 0
+	for _, threshold := range e.Rules.Stars {
+		if state.Score >= threshold {
+			stars++
+		}
+	}
+	return stars
+}
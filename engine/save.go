@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SaveVersion is the version of the save format read by Restore
+const SaveVersion = 4
+
+var ErrInvalidSave = errors.New("invalid save")
+
+// Save is everything needed to resume a game in progress
+type Save struct {
+	Version      int
+	Mode         GameMode
+	Seed         uint64
+	Rules        Rules
+	TimeLimit    time.Duration
+	TimeLeft     time.Duration
+	State        State
+	History      History
+	HistoryLimit int
+}
+
+func (e *Engine) Save() Save {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return Save{
+		Version:      SaveVersion,
+		Mode:         e.Mode,
+		Seed:         e.Seed,
+		Rules:        e.Rules,
+		TimeLimit:    e.TimeLimit,
+		TimeLeft:     e.timeLeft(),
+		State:        e.state.clone(),
+		History:      History{Turns: append([]Turn{}, e.history.Turns...), Position: e.history.Position},
+		HistoryLimit: e.HistoryLimit,
+	}
+}
+
+// SaveFile writes the game to a file, through a temporary file so that a crash never leaves half a save
+func (e *Engine) SaveFile(path string) error {
+	data, err := json.Marshal(e.Save())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Restore returns an engine resuming a saved game, the countdown of a timed game starts again from its time left
+func Restore(save Save) (*Engine, error) {
+	return restore(save, nil)
+}
+
+func restore(save Save, clock Clock) (*Engine, error) {
+	if save.Version != SaveVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidSave, save.Version, SaveVersion)
+	}
+
+	if save.Mode < Classic || save.Mode > MoveLimited {
+		return nil, fmt.Errorf("%w: unknown mode %d", ErrInvalidSave, save.Mode)
+	}
+
+	if !save.Rules.isValid() {
+		return nil, fmt.Errorf("%w: broken rules", ErrInvalidSave)
+	}
+
+	if !save.State.Board.isConsistent() {
+		return nil, fmt.Errorf("%w: broken board", ErrInvalidSave)
+	}
+
+	// an undo must find a board of the same size
+	for i, turn := range save.History.Turns {
+		before := turn.Before.Board
+		if !before.isConsistent() || before.Width != save.State.Width() || before.Height != save.State.Height() {
+			return nil, fmt.Errorf("%w: broken board in turn %d of the history", ErrInvalidSave, i)
+		}
+	}
+
+	if save.History.Position < 0 || save.History.Position > len(save.History.Turns) {
+		return nil, fmt.Errorf("%w: history position %d out of %d turns", ErrInvalidSave, save.History.Position, len(save.History.Turns))
+	}
+
+	e := &Engine{
+		Mode:         save.Mode,
+		Seed:         save.Seed,
+		Clock:        clock,
+		Rules:        save.Rules,
+		TimeLimit:    save.TimeLimit,
+		HistoryLimit: save.HistoryLimit,
+		history:      save.History,
+	}
+
+	// the hash is not saved
+	save.State.Board.rehash()
+	for i := range e.history.Turns {
+		e.history.Turns[i].Before.Board.rehash()
+	}
+
+	if e.Mode == Timed {
+		e.startedAt = e.now().Add(save.TimeLeft - e.TimeLimit - time.Duration(save.State.BonusSeconds)*time.Second)
+	}
+
+	e.commit(save.State)
+
+	return e, nil
+}
+
+func ReadSave(path string) (Save, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Save{}, err
+	}
+
+	var save Save
+	if err := json.Unmarshal(data, &save); err != nil {
+		return Save{}, fmt.Errorf("%w: %s: %v", ErrInvalidSave, path, err)
+	}
+
+	return save, nil
+}
+
+func LoadSave(path string) (*Engine, error) {
+	save, err := ReadSave(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Restore(save)
+}
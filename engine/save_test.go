@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// savedGame plays a few turns of a game with jelly and returns its save, through JSON as in a file
+func savedGame(t *testing.T) Save {
+	t.Helper()
+
+	e := &Engine{Seed: 1, Rules: Rules{MoveLimit: 20}}
+	state := e.Init()
+	state.Board.SetJelly(Coord{X: 4, Y: 4}, 2)
+	e.initBoard(state)
+
+	for i := 0; i < 3; i++ {
+		move, _ := e.FindHint(e.Snapshot())
+		if err := e.PlayTurn(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := json.Marshal(e.Save())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var save Save
+	if err := json.Unmarshal(data, &save); err != nil {
+		t.Fatal(err)
+	}
+
+	return save
+}
+
+func TestRestore(t *testing.T) {
+	save := savedGame(t)
+
+	e, err := Restore(save)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := e.Undo(); err != nil {
+			t.Fatalf("undo %d: %v", i, err)
+		}
+	}
+
+	if state := e.Snapshot(); state.Moves != 0 || state.Board.GetJelly(Coord{X: 4, Y: 4}) == 0 {
+		t.Errorf("undone to %d moves and %d jelly", state.Moves, state.Board.JellyLeft())
+	}
+}
+
+func TestRestoreRejectsBrokenHistory(t *testing.T) {
+	for name, corrupt := range map[string]func(*Save){
+		"jelly": func(save *Save) {
+			save.History.Turns[1].Before.Board.Jelly = save.History.Turns[1].Before.Board.Jelly[:5]
+		},
+		"cells": func(save *Save) {
+			save.History.Turns[0].Before.Board.Cells = nil
+		},
+		"size": func(save *Save) {
+			before := &save.History.Turns[2].Before.Board
+			before.Width, before.Height = before.Height, before.Width
+			before.Width++
+			before.Cells = make([]Cell, before.Width*before.Height)
+			before.Jelly = nil
+		},
+		"position": func(save *Save) {
+			save.History.Position = 4
+		},
+	} {
+		save := savedGame(t)
+		corrupt(&save)
+
+		if _, err := Restore(save); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("%s: Restore returned %v, expected ErrInvalidSave", name, err)
+		}
+	}
+}
+
+func TestRestoreRejectsBrokenRules(t *testing.T) {
+	for name, corrupt := range map[string]func(*Save){
+		"collect color": func(save *Save) {
+			save.Rules.Objectives = []Objective{{Kind: CollectObjective, Target: 10, Color: 9}}
+		},
+		"objective": func(save *Save) {
+			save.Rules.Objectives = []Objective{{Kind: 7, Target: 10}}
+		},
+		"colors": func(save *Save) {
+			save.Rules.Colors = []Cell{Red, Blocker, Green}
+		},
+		"refill": func(save *Save) {
+			save.Rules.Refill.BlockerChance = 2
+		},
+		"mode": func(save *Save) {
+			save.Mode = 12
+		},
+	} {
+		save := savedGame(t)
+		corrupt(&save)
+
+		if _, err := Restore(save); !errors.Is(err, ErrInvalidSave) {
+			t.Errorf("%s: Restore returned %v, expected ErrInvalidSave", name, err)
+		}
+	}
+}
+
+func TestPauseStopsTheCountdown(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	e := &Engine{Seed: 1, Clock: clock}
+	e.InitTimed(time.Minute)
+
+	clock.Advance(10 * time.Second)
+	e.Pause()
+	clock.Advance(time.Hour)
+
+	if left := e.TimeLeft(); left != 50*time.Second {
+		t.Errorf("%v left while paused, expected 50s", left)
+	}
+	if e.IsGameOver() {
+		t.Error("the time ran out while paused")
+	}
+
+	e.Resume()
+	clock.Advance(20 * time.Second)
+
+	if left := e.TimeLeft(); left != 30*time.Second {
+		t.Errorf("%v left after the pause, expected 30s", left)
+	}
+}
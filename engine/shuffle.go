@@ -0,0 +1,71 @@
+package engine
+
+// attempts at shuffling a board without any valid move before giving up
+const shuffleAttempts = 100
+
+// hasValidMove tells whether at least one swap makes a match, it stops at the first one
+func (e *Engine) hasValidMove(state State) bool {
+	for i := 0; i < state.Height(); i++ {
+		for j := 0; j < state.Width(); j++ {
+			c := Coord{X: j, Y: i}
+
+			for _, dir := range []Direction{Right, Down} {
+				if e.checkAction(state, Action{From: c, To: GetNeighbor(dir, c)}) == nil {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// shuffleInPlace moves the candies around until the board has a valid move and no match, drawing from the random
+// generator of the state. It returns the cells of the shuffled candies, or false and the board unchanged when
+// no such board was found, such as on a board with too few candies.
+func (e *Engine) shuffleInPlace(state *State) ([]Coord, bool) {
+	var coords []Coord
+	var candies []Cell
+
+	for i := 0; i < state.Height(); i++ {
+		for j := 0; j < state.Width(); j++ {
+			c := Coord{X: j, Y: i}
+			if state.GetCell(c).IsCandy() {
+				coords = append(coords, c)
+				candies = append(candies, state.GetCell(c))
+			}
+		}
+	}
+
+	original := append([]Cell{}, candies...)
+
+	for attempt := 0; attempt < shuffleAttempts; attempt++ {
+		for i := len(candies) - 1; i > 0; i-- {
+			k := state.Rand.Intn(i + 1)
+			candies[i], candies[k] = candies[k], candies[i]
+		}
+
+		for i, c := range coords {
+			state.SetCell(c, candies[i])
+		}
+
+		if !e.hasMatch(*state, coords) && e.hasValidMove(*state) {
+			return coords, true
+		}
+	}
+
+	for i, c := range coords {
+		state.SetCell(c, original[i])
+	}
+
+	return nil, false
+}
+
+func (e *Engine) hasMatch(state State, coords []Coord) bool {
+	for _, c := range coords {
+		if e.makesMatch(state, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+// a board of four colors where no swap lines up three candies
+const stuckBoard = `
+R Y G B R Y
+G B R Y G B
+Y G B R Y G
+B R Y G B R
+R Y G B R Y
+G B R Y G B
+`
+
+func TestResolveShufflesStuckBoard(t *testing.T) {
+	e := &Engine{Mode: Zen}
+
+	state, err := ParseBoard(stuckBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Rand = NewRandom(1)
+
+	if e.hasValidMove(state) {
+		t.Fatal("the test board has a valid move")
+	}
+
+	var timeline Timeline
+	shuffled := e.resolve(state, &timeline)
+
+	if len(timeline) != 1 || timeline[0].Kind != ShufflePhase {
+		t.Fatalf("timeline %v, expected a single shuffle", timeline)
+	}
+
+	if !e.hasValidMove(shuffled) {
+		t.Errorf("no valid move after the shuffle:\n%v", shuffled)
+	}
+
+	if e.hasMatch(shuffled, timeline[0].Cells) {
+		t.Errorf("the shuffle made a match:\n%v", shuffled)
+	}
+
+	if !slices.Equal(slices.Sorted(slices.Values(state.Board.Cells)), slices.Sorted(slices.Values(shuffled.Board.Cells))) {
+		t.Errorf("the shuffle changed the candies:\n%v\n%v", state, shuffled)
+	}
+
+	inPlace := state.clone()
+	e.resolveInPlace(&inPlace)
+
+	if !inPlace.Equal(shuffled) {
+		t.Errorf("resolveInPlace shuffled to\n%v\nresolve to\n%v", inPlace, shuffled)
+	}
+}
+
+func TestShuffleGivesUpWithoutEnoughCandies(t *testing.T) {
+	e := &Engine{}
+
+	state, err := ParseBoard("R X Y\nX G X\nB X P")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := state.clone()
+
+	if _, ok := e.shuffleInPlace(&state); ok {
+		t.Fatal("a board without any possible match was shuffled")
+	}
+
+	// only the random generator moved on
+	if !slices.Equal(state.Board.Cells, before.Board.Cells) {
+		t.Errorf("the board changed:\n%v", state)
+	}
+}
@@ -1,8 +1,15 @@
 package engine
 
 type State struct {
-	Board Board
-	Score int
+	Board        Board
+	Score        int
+	BonusSeconds int
+	Rand         Random
+	// swaps played so far
+	Moves int
+	// exploded candies by color
+	Cleared     [NumColors + 1]int
+	Ingredients int
 }
 
 func (s *State) SwapCells(from, to Coord) {
@@ -19,6 +26,20 @@ func (s *State) GetCell(coord Coord) Cell {
 	return s.Board.GetCell(coord)
 }
 
+func (s *State) IsInside(coord Coord) bool {
+	return coord.X >= 0 && coord.X < s.Width() && coord.Y >= 0 && coord.Y < s.Height()
+}
+
+// IsExit tells whether an ingredient on this cell is collected: no playable cell is below it
+func (s *State) IsExit(coord Coord) bool {
+	for i := coord.Y + 1; i < s.Height(); i++ {
+		if s.GetCell(Coord{X: coord.X, Y: i}) != Void {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *State) Width() int {
 	return s.Board.Width
 }
@@ -29,22 +50,18 @@ func (s *State) Height() int {
 
 func (s *State) clone() State {
 	// deep copy
-	newBoard := Board{
-		Width:  s.Width(),
-		Height: s.Height(),
-		Cells:  make([][]Cell, s.Height()),
-	}
+	newState := *s
+	newState.Board = Board{}
+	newState.Board.copyFrom(&s.Board)
 
-	for i := 0; i < s.Height(); i++ {
-		newBoard.Cells[i] = make([]Cell, s.Width())
-		for j := 0; j < s.Width(); j++ {
-			c := Coord{X: j, Y: i}
-			newBoard.SetCell(c, s.GetCell(c))
-		}
-	}
+	return newState
+}
 
-	return State{
-		Board: newBoard,
-		Score: s.Score,
-	}
+// CopyFrom makes the state a deep copy of another one, reusing the slices of its board when they are large enough:
+// a search can keep one state per depth instead of allocating a new one per move
+func (s *State) CopyFrom(other State) {
+	board := s.Board
+	*s = other
+	s.Board = board
+	s.Board.copyFrom(&other.Board)
 }
@@ -0,0 +1,59 @@
+package engine
+
+import "testing"
+
+// benchmarkGame returns a board and the moves of a game played from it
+func benchmarkGame(b *testing.B) (*Engine, State, []Action) {
+	b.Helper()
+
+	e := &Engine{Seed: 1}
+	e.InitRandom()
+
+	start := e.Snapshot()
+	state := start
+	var moves []Action
+
+	for i := 0; i < 20; i++ {
+		move, ok := e.FindHint(state)
+		if !ok {
+			break
+		}
+
+		moves = append(moves, move)
+		state, _, _ = e.Play(state, move)
+	}
+
+	return e, start, moves
+}
+
+func BenchmarkPlay(b *testing.B) {
+	e, start, moves := benchmarkGame(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		state := start
+		for _, move := range moves {
+			var err error
+			if state, _, err = e.Play(state, move); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkPlayInPlace(b *testing.B) {
+	e, start, moves := benchmarkGame(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var state State
+	for i := 0; i < b.N; i++ {
+		state.CopyFrom(start)
+		for _, move := range moves {
+			if err := e.PlayInPlace(&state, move); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Plain text board notation: one row per line, one letter per cell
+ * R Y G B P O for the colors, . for Empty, X for a Blocker, _ for a Void, I for an Ingredient,
+ * followed by + for a time bonus candy, or by the hit points of a blocker when it has more than one.
+ * Cells may be separated by spaces, lines starting with # are comments.
+ */
+
+var ErrInvalidBoard = errors.New("invalid board")
+
+var colorLetters = map[Cell]byte{
+	Empty:      '.',
+	Red:        'R',
+	Yellow:     'Y',
+	Green:      'G',
+	Blue:       'B',
+	Purple:     'P',
+	Orange:     'O',
+	Blocker:    'X',
+	Void:       '_',
+	Ingredient: 'I',
+}
+
+var specialSuffixes = map[Special]byte{
+	TimeBonus: '+',
+}
+
+func (c Cell) String() string {
+	letter, ok := colorLetters[c.Color()]
+	if !ok {
+		return "?"
+	}
+
+	text := string(letter)
+
+	if suffix, ok := specialSuffixes[c.Special()]; ok {
+		text += string(suffix)
+	}
+
+	if c.HP() > 1 {
+		text += strconv.Itoa(c.HP())
+	}
+
+	return text
+}
+
+func (s State) String() string {
+	var builder strings.Builder
+
+	for i := 0; i < s.Height(); i++ {
+		for j := 0; j < s.Width(); j++ {
+			if j > 0 {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(s.GetCell(Coord{X: j, Y: i}).String())
+		}
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+// ParseBoard reads a board in text notation, the returned state has no score
+func ParseBoard(text string) (State, error) {
+	var rows [][]Cell
+
+	for lineIndex, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		row, err := parseRow(line)
+		if err != nil {
+			return State{}, fmt.Errorf("%w: line %d: %v", ErrInvalidBoard, lineIndex+1, err)
+		}
+
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return State{}, fmt.Errorf("%w: line %d: %d cells, expected %d", ErrInvalidBoard, lineIndex+1, len(row), len(rows[0]))
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return State{}, fmt.Errorf("%w: no rows", ErrInvalidBoard)
+	}
+
+	board := NewBoard(len(rows[0]), len(rows))
+	for i, row := range rows {
+		copy(board.Cells[i*board.Width:], row)
+	}
+	board.rehash()
+
+	return State{Board: board}, nil
+}
+
+func parseRow(line string) ([]Cell, error) {
+	var row []Cell
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		if char == ' ' || char == '\t' {
+			continue
+		}
+
+		if color, ok := letterColor(char); ok {
+			row = append(row, color)
+			continue
+		}
+
+		if char >= '2' && char <= '9' {
+			if len(row) == 0 || row[len(row)-1] != Blocker {
+				return nil, fmt.Errorf("misplaced hit points %q", char)
+			}
+
+			row[len(row)-1] = NewBlocker(int(char - '0'))
+			continue
+		}
+
+		special, ok := suffixSpecial(char)
+		if !ok {
+			return nil, fmt.Errorf("unknown cell %q", char)
+		}
+
+		if len(row) == 0 || !row[len(row)-1].IsCandy() || row[len(row)-1].Special() != NoSpecial {
+			return nil, fmt.Errorf("misplaced suffix %q", char)
+		}
+
+		row[len(row)-1] = row[len(row)-1].WithSpecial(special)
+	}
+
+	return row, nil
+}
+
+func letterColor(letter byte) (Cell, bool) {
+	for color, l := range colorLetters {
+		if l == letter {
+			return color, true
+		}
+	}
+	return Empty, false
+}
+
+func suffixSpecial(suffix byte) (Special, bool) {
+	for special, s := range specialSuffixes {
+		if s == suffix {
+			return special, true
+		}
+	}
+	return NoSpecial, false
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBoard(t *testing.T) {
+	state, err := ParseBoard(`
+		# every kind of cell
+		R Y G B
+		P O . X
+		_ I R+ X3
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state.Width() != 4 || state.Height() != 3 {
+		t.Fatalf("board is %dx%d, expected 4x3", state.Width(), state.Height())
+	}
+
+	expected := []Cell{
+		Red, Yellow, Green, Blue,
+		Purple, Orange, Empty, Blocker,
+		Void, Ingredient, Red.WithSpecial(TimeBonus), NewBlocker(3),
+	}
+
+	for i, cell := range expected {
+		c := Coord{X: i % 4, Y: i / 4}
+		if state.GetCell(c) != cell {
+			t.Errorf("%v is %v, expected %v", c, state.GetCell(c), cell)
+		}
+	}
+
+	hash := state.Board.Hash()
+	state.Board.rehash()
+	if hash != state.Board.Hash() {
+		t.Error("the parsed board is not hashed")
+	}
+
+	compact, err := ParseBoard("RYGB\nPO.X\n_IR+X3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compact.Equal(state) {
+		t.Errorf("the cells read without spaces are\n%v", compact)
+	}
+}
+
+func TestParseBoardErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"# only a comment",
+		"R Y\nG",
+		"R Z",
+		"R 3",
+		"X+",
+		"R++",
+	} {
+		if _, err := ParseBoard(text); !errors.Is(err, ErrInvalidBoard) {
+			t.Errorf("ParseBoard(%q) returned %v, expected ErrInvalidBoard", text, err)
+		}
+	}
+}
+
+func TestBoardTextRoundTrip(t *testing.T) {
+	for seed := uint64(1); seed <= 20; seed++ {
+		e := &Engine{Seed: seed, Rules: Rules{Refill: Refill{BlockerChance: 0.1, TimeBonusChance: 0.1}}}
+		e.InitRandom()
+
+		state := e.Snapshot()
+
+		// a few cells the generator does not make
+		state.SetCell(Coord{X: 0, Y: 0}, Void)
+		state.SetCell(Coord{X: 1, Y: 0}, Ingredient)
+		state.SetCell(Coord{X: 2, Y: 0}, NewBlocker(int(seed%MaxBlockerHP)+1))
+
+		parsed, err := ParseBoard(state.String())
+		if err != nil {
+			t.Fatalf("seed %d: %v\n%v", seed, err, state)
+		}
+
+		if parsed.String() != state.String() || parsed.Board.Hash() != state.Board.Hash() {
+			t.Errorf("seed %d: parsed\n%v\nfrom\n%v", seed, parsed, state)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+type PhaseKind int
+
+const (
+	SwapPhase PhaseKind = iota
+	ExplodePhase
+	ScorePhase
+	FallPhase
+	RefillPhase
+	// the board had no valid move left and was shuffled
+	ShufflePhase
+)
+
+func (k PhaseKind) String() string {
+	switch k {
+	case SwapPhase:
+		return "swap"
+	case ExplodePhase:
+		return "explode"
+	case ScorePhase:
+		return "score"
+	case FallPhase:
+		return "fall"
+	case RefillPhase:
+		return "refill"
+	case ShufflePhase:
+		return "shuffle"
+	default:
+		return "unknown"
+	}
+}
+
+// Move is a candy falling from one cell to another
+type Move struct {
+	From, To Coord
+}
+
+// Phase is a step of a resolved turn, with the state right after it
+type Phase struct {
+	Kind PhaseKind
+	// swapped, exploded, refilled or shuffled cells
+	Cells []Coord
+	// fallen candies
+	Moves      []Move
+	ScoreDelta int
+	State      State
+}
+
+type Timeline []Phase
+
+// Mask converts the cells of a phase to a grid of flags, as used by the UI animations
+func (p *Phase) Mask() [][]bool {
+	cells := append([]Coord{}, p.Cells...)
+
+	for _, m := range p.Moves {
+		cells = append(cells, m.To)
+	}
+
+	return cellsMask(p.State, cells)
+}
+
+func cellsMask(state State, cells []Coord) [][]bool {
+	mask := make([][]bool, state.Height())
+	for i := 0; i < state.Height(); i++ {
+		mask[i] = make([]bool, state.Width())
+	}
+
+	for _, c := range cells {
+		mask[c.Y][c.X] = true
+	}
+
+	return mask
+}
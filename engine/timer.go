@@ -0,0 +1,98 @@
+package engine
+
+import "time"
+
+// default chance for a refilled candy to carry bonus time in timed mode
+const TimeBonusChance = 0.04
+
+// seconds added to the countdown when a bonus time candy explodes
+const TimeBonusSeconds = 5
+
+func (e *Engine) InitTimed(timeLimit time.Duration) {
+	e.Mode = Timed
+	e.TimeLimit = timeLimit
+	e.timeUp = false
+	e.gameOverPublished = false
+
+	if e.Rules.Refill.TimeBonusChance == 0 {
+		e.Rules.Refill.TimeBonusChance = TimeBonusChance
+	}
+
+	e.InitRandom()
+
+	e.startedAt = e.now()
+}
+
+func (e *Engine) now() time.Time {
+	if e.Clock == nil {
+		return time.Now()
+	}
+	return e.Clock.Now()
+}
+
+// TimeLeft returns the remaining time of a timed game, bonus time included
+func (e *Engine) TimeLeft() time.Duration {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.timeLeft()
+}
+
+func (e *Engine) timeLeft() time.Duration {
+	if e.Mode != Timed || e.timeUp {
+		return 0
+	}
+
+	now := e.now()
+	if !e.pausedAt.IsZero() {
+		now = e.pausedAt
+	}
+
+	deadline := e.startedAt.Add(e.TimeLimit + time.Duration(e.state.BonusSeconds)*time.Second)
+	left := deadline.Sub(now)
+
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Pause stops the countdown of a timed game until Resume
+func (e *Engine) Pause() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.Mode == Timed && e.pausedAt.IsZero() {
+		e.pausedAt = e.now()
+	}
+}
+
+// Resume starts the countdown again from the time left when it was paused
+func (e *Engine) Resume() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.pausedAt.IsZero() {
+		return
+	}
+
+	e.startedAt = e.startedAt.Add(e.now().Sub(e.pausedAt))
+	e.paused += e.now().Sub(e.pausedAt)
+	e.pausedAt = time.Time{}
+}
+
+// pausedFor returns the time the countdown spent paused, the pause going on included
+func (e *Engine) pausedFor() time.Duration {
+	paused := e.paused
+	if !e.pausedAt.IsZero() {
+		paused += e.now().Sub(e.pausedAt)
+	}
+	return paused
+}
+
+// checkTimeUp latches the end of a timed game, so bonus time exploding later in the cascade cannot revive it
+func (e *Engine) checkTimeUp() {
+	if e.Mode == Timed && !e.timeUp && e.timeLeft() == 0 {
+		e.timeUp = true
+	}
+}
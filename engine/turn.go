@@ -0,0 +1,76 @@
+package engine
+
+// PlayTurn plays an action on the engine state, then publishes the events of the whole turn.
+// The events are published outside the lock: a turn played while they are being published is rejected.
+func (e *Engine) PlayTurn(action Action) error {
+	e.mutex.Lock()
+
+	if e.resolving {
+		e.mutex.Unlock()
+		return &ActionError{Action: action, Err: ErrBusy}
+	}
+
+	if e.isGameOver() {
+		e.mutex.Unlock()
+		return &ActionError{Action: action, Err: ErrGameOver}
+	}
+
+	newState, timeline, err := e.Play(e.state, action)
+	if err != nil {
+		e.mutex.Unlock()
+		return err
+	}
+
+	e.history.push(Turn{Before: e.state, Action: action}, e.historyLimit())
+	e.commit(newState)
+	e.publishTurn(action, newState, timeline)
+
+	return nil
+}
+
+// publishTurn must be called with the engine locked, it unlocks it while the events are published
+func (e *Engine) publishTurn(action Action, newState State, timeline Timeline) {
+	e.resolving = true
+	e.mutex.Unlock()
+
+	for _, phase := range timeline {
+		e.Events.Publish(phaseEvent(phase, action))
+	}
+
+	e.mutex.Lock()
+	e.resolving = false
+	e.mutex.Unlock()
+
+	e.Events.Publish(TurnSettled{State: newState})
+
+	e.Tick()
+}
+
+func (e *Engine) IsGameOver() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.isGameOver()
+}
+
+func (e *Engine) isGameOver() bool {
+	e.checkTimeUp()
+	return e.timeUp || e.noMoves || e.MovesLeft(e.state) == 0 || (e.IsWon(e.state) && e.EndsOnWin())
+}
+
+// Tick publishes GameOver once, as soon as the game is over
+func (e *Engine) Tick() {
+	e.mutex.Lock()
+
+	if e.gameOverPublished || !e.isGameOver() {
+		e.mutex.Unlock()
+		return
+	}
+
+	e.gameOverPublished = true
+	// the listeners read it outside the lock
+	state := e.state.clone()
+	e.mutex.Unlock()
+
+	e.Events.Publish(GameOver{State: state, Won: e.IsWon(state), Stars: e.Stars(state)})
+}
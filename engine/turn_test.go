@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readState reads every cell, so that the race detector sees a state shared with the engine
+func readState(state State) int {
+	sum := 0
+	for _, cell := range state.Board.Cells {
+		sum += int(cell)
+	}
+	for _, layers := range state.Board.Jelly {
+		sum += layers
+	}
+	return sum
+}
+
+// TestConcurrentTurns simulates input from several goroutines, run it with go test -race
+func TestConcurrentTurns(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	e := &Engine{Seed: 1, Clock: clock}
+	e.InitTimed(time.Minute)
+
+	var mutex sync.Mutex
+	events := 0
+
+	e.Events.Subscribe(func(event Event) {
+		var state State
+		switch event := event.(type) {
+		case TurnSettled:
+			state = event.State
+		case TurnUndone:
+			state = event.State
+		case GameOver:
+			state = event.State
+		default:
+			return
+		}
+
+		readState(state)
+
+		mutex.Lock()
+		events++
+		mutex.Unlock()
+	})
+
+	var wg sync.WaitGroup
+
+	for player := 0; player < 4; player++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 50; i++ {
+				move, ok := e.FindHint(e.Snapshot())
+				if !ok {
+					return
+				}
+
+				err := e.PlayTurn(move)
+				if err != nil && !errors.Is(err, ErrBusy) && !errors.Is(err, ErrNoMatch) && !errors.Is(err, ErrGameOver) {
+					t.Errorf("PlayTurn(%v): %v", move, err)
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			err := e.Undo()
+			if err != nil && !errors.Is(err, ErrBusy) && !errors.Is(err, ErrNothingToUndo) && !errors.Is(err, ErrGameOver) {
+				t.Errorf("Undo: %v", err)
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			e.Tick()
+			readState(e.Snapshot())
+		}
+
+		// ends the game while the others may still be playing, beyond any bonus time they may have won
+		clock.Advance(time.Hour)
+		e.Tick()
+	}()
+
+	wg.Wait()
+
+	if !e.IsGameOver() {
+		t.Error("the game is not over after the time limit")
+	}
+
+	if events == 0 {
+		t.Error("no event was published")
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import "time"
+
+// ZenLevel is a step of the zen mode difficulty, reached once the score is at least MinScore
+type ZenLevel struct {
+	MinScore      int
+	Colors        int
+	BlockerChance float64
+	HintDelay     time.Duration
+}
+
+var ZenProgression = []ZenLevel{
+	{MinScore: 0, Colors: 4, BlockerChance: 0, HintDelay: 8 * time.Second},
+	{MinScore: 100, Colors: 5, BlockerChance: 0.01, HintDelay: 6 * time.Second},
+	{MinScore: 300, Colors: 6, BlockerChance: 0.02, HintDelay: 5 * time.Second},
+	{MinScore: 700, Colors: 6, BlockerChance: 0.04, HintDelay: 3 * time.Second},
+	{MinScore: 1500, Colors: 6, BlockerChance: 0.07, HintDelay: 2 * time.Second},
+}
+
+// hint delay of the modes without progression
+const DefaultHintDelay = 5 * time.Second
+
+func (e *Engine) InitZen() {
+	e.Mode = Zen
+	e.InitRandom()
+}
+
+func (e *Engine) ZenLevel() ZenLevel {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return zenLevel(e.state.Score)
+}
+
+func zenLevel(score int) ZenLevel {
+	level := ZenProgression[0]
+
+	for _, l := range ZenProgression {
+		if score >= l.MinScore {
+			level = l
+		}
+	}
+
+	return level
+}
+
+// HintDelay is how long the player can stay idle before a hint is shown
+func (e *Engine) HintDelay() time.Duration {
+	if e.Mode == Zen {
+		return e.ZenLevel().HintDelay
+	}
+	return DefaultHintDelay
+}
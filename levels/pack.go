@@ -0,0 +1,143 @@
+package levels
+
+import (
+	"bytes"
+	"candycrush/engine"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+// the metadata file at the root of a pack directory
+const packFile = "pack.json"
+
+//go:embed packs
+var builtin embed.FS
+
+var ErrInvalidPack = errors.New("invalid pack")
+var ErrPackNotFound = errors.New("pack not found")
+
+// Pack is an ordered list of levels with its metadata
+type Pack struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Author      string `json:"author,omitempty"`
+	// level files, relative to the pack directory, in play order
+	Files  []string        `json:"levels"`
+	Levels []*engine.Level `json:"-"`
+}
+
+// Level returns a level by its number, starting at 1
+func (p *Pack) Level(number int) (*engine.Level, error) {
+	if number < 1 || number > len(p.Levels) {
+		return nil, fmt.Errorf("pack %s has no level %d, it has %d levels", p.Name, number, len(p.Levels))
+	}
+	return p.Levels[number-1], nil
+}
+
+// Builtin returns the packs compiled in the binary
+func Builtin() ([]*Pack, error) {
+	return loadPacks(builtin, "packs")
+}
+
+// LoadDir returns the packs of a directory: either the directory itself is a pack, or each of its subdirectories is
+func LoadDir(dir string) ([]*Pack, error) {
+	fsys := os.DirFS(dir)
+
+	if _, err := fs.Stat(fsys, packFile); err == nil {
+		pack, err := loadPack(fsys, ".")
+		if err != nil {
+			return nil, err
+		}
+		return []*Pack{pack}, nil
+	}
+
+	return loadPacks(fsys, ".")
+}
+
+// Find returns the pack with the given name
+func Find(packs []*Pack, name string) (*Pack, error) {
+	for _, pack := range packs {
+		if pack.Name == name {
+			return pack, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPackNotFound, name)
+}
+
+func loadPacks(fsys fs.FS, dir string) ([]*Pack, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var packs []*Pack
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		packDir := path.Join(dir, entry.Name())
+		if _, err := fs.Stat(fsys, path.Join(packDir, packFile)); err != nil {
+			continue
+		}
+
+		pack, err := loadPack(fsys, packDir)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Name < packs[j].Name
+	})
+
+	return packs, nil
+}
+
+func loadPack(fsys fs.FS, dir string) (*Pack, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, packFile))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var pack Pack
+	if err := decoder.Decode(&pack); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPack, dir, err)
+	}
+
+	if pack.Name == "" {
+		return nil, fmt.Errorf("%w: %s: missing name", ErrInvalidPack, dir)
+	}
+
+	if len(pack.Files) == 0 {
+		return nil, fmt.Errorf("%w: %s: no levels", ErrInvalidPack, pack.Name)
+	}
+
+	for _, file := range pack.Files {
+		data, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPack, pack.Name, err)
+		}
+
+		level, err := engine.ParseLevel(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", pack.Name, file, err)
+		}
+
+		pack.Levels = append(pack.Levels, level)
+	}
+
+	return &pack, nil
+}
@@ -1,10 +1,90 @@
 package main
 
 import (
+	"candycrush/ai"
 	"candycrush/controller"
+	"candycrush/engine"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 )
 
 func main() {
-	cont := controller.NewController()
+	modeName := flag.String("mode", "zen", "game mode: zen, timed or classic, the levels of -level and -pack bring their own")
+	timeLimit := flag.Duration("time", 60*time.Second, "countdown of the timed mode")
+	levelPath := flag.String("level", "", "JSON level file to play")
+	packsDir := flag.String("packs", "", "directory of user level packs")
+	packName := flag.String("pack", "", "level pack to play")
+	packLevel := flag.Int("pack-level", 1, "number of the level to play in the pack")
+	savePath := flag.String("save", controller.DefaultSavePath(), "file the game is saved to on close and resumed from, empty to disable")
+	recordPath := flag.String("record", "", "file the replay of the game is written to on close")
+	checkReplay := flag.String("check-replay", "", "replay file to play again, checking its final score, then exit")
+	player := flag.String("player", ai.DefaultPlayer, "ai player showing the hints: "+strings.Join(ai.PlayerNames(), ", "))
+	autoplay := flag.Bool("autoplay", false, "let the ai player play the game")
+	moveTime := flag.Duration("move-time", controller.DefaultMoveTime, "thinking time of the ai player per move")
+	listPacks := flag.Bool("list-packs", false, "list the level packs and exit")
+	flag.Parse()
+
+	if *listPacks {
+		printPacks(*packsDir)
+		return
+	}
+
+	if *checkReplay != "" {
+		runReplay(*checkReplay)
+		return
+	}
+
+	mode, ok := engine.ParseGameMode(*modeName)
+	if !ok {
+		log.Fatalf("unknown game mode: %s", *modeName)
+	}
+
+	cont, err := controller.NewController(controller.Options{
+		Mode:       mode,
+		TimeLimit:  *timeLimit,
+		LevelPath:  *levelPath,
+		PackName:   *packName,
+		PackLevel:  *packLevel,
+		PacksDir:   *packsDir,
+		SavePath:   *savePath,
+		RecordPath: *recordPath,
+		Player:     *player,
+		Autoplay:   *autoplay,
+		MoveTime:   *moveTime,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 	cont.Run()
 }
+
+func printPacks(dir string) {
+	packs, err := controller.LoadPacks(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, pack := range packs {
+		fmt.Printf("%s: %s (%d levels)\n", pack.Name, pack.Title, len(pack.Levels))
+		for i, level := range pack.Levels {
+			fmt.Printf("  %d. %s\n", i+1, level.Name)
+		}
+	}
+}
+
+func runReplay(path string) {
+	replay, err := engine.ReadReplay(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	state, err := replay.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%d actions replayed, final score %d\n", len(replay.Actions), state.Score)
+}
@@ -18,6 +18,9 @@ var darkBlueColor = color.NRGBA{R: 0, G: 0, B: 127, A: 255}
 var purpleColor = color.NRGBA{R: 150, G: 0, B: 150, A: 255}
 var darkPurpleColor = color.NRGBA{R: 75, G: 0, B: 75, A: 255}
 var orangeColor = color.NRGBA{R: 255, G: 165, B: 0, A: 255}
+var grayColor = color.NRGBA{R: 128, G: 128, B: 128, A: 255}
+var darkGrayColor = color.NRGBA{R: 64, G: 64, B: 64, A: 255}
+var brownColor = color.NRGBA{R: 139, G: 69, B: 19, A: 255}
 var maroon = color.NRGBA{R: 127, G: 0, B: 0, A: 255}
 var slightDark = color.NRGBA{R: 0, G: 0, B: 0, A: 127}
 
@@ -25,6 +28,7 @@ var slightGreen = color.NRGBA{R: 0, G: 255, B: 0, A: 127}
 var slightBlue = color.NRGBA{R: 0, G: 0, B: 255, A: 127}
 var slightRed = color.NRGBA{R: 255, G: 0, B: 0, A: 127}
 var slightOrange = color.NRGBA{R: 255, G: 165, B: 0, A: 127}
+var slightPink = color.NRGBA{R: 255, G: 105, B: 180, A: 127}
 
 func randomColor() color.NRGBA {
 	return color.NRGBA{
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"candycrush/engine"
+	"gioui.org/app"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"time"
+)
+
+// AskResume shows a prompt over the board until the player chooses between the saved game and a new one
+func (ui *UI) AskResume(onChoice func(resume bool)) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	ui.onResumeChoice = onChoice
+}
+
+// IsAskingResume tells whether the player has not answered the resume prompt yet
+func (ui *UI) IsAskingResume() bool {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	return ui.onResumeChoice != nil
+}
+
+// Reset drops the queued animations and shows another game, the window follows the size of its board
+func (ui *UI) Reset(state engine.State) {
+	ui.mutex.Lock()
+	ui.events = nil
+	ui.hint = nil
+	ui.mutex.Unlock()
+
+	ui.pendingState = nil
+	ui.gameOver = false
+	ui.state = state
+	ui.clickables = make([]widget.Clickable, ui.Width()*ui.Height())
+	ui.lastInteraction = time.Now()
+	ui.SetScore(state.Score)
+	ui.SetAnimStep(Idle)
+	ui.SetAnimStart()
+
+	if ui.window != nil {
+		ui.window.Option(app.Size(
+			unit.Dp(ui.Width())*cellSizeDp,
+			unit.Dp(ui.Height())*cellSizeDp,
+		))
+	}
+}
+
+func (ui *UI) drawResumePrompt(theme *material.Theme, gtx layout.Context) {
+	ui.mutex.Lock()
+	onChoice := ui.onResumeChoice
+	ui.mutex.Unlock()
+
+	if onChoice == nil {
+		return
+	}
+
+	resume := ui.resumeButton.Clicked(gtx)
+	newGame := ui.newGameButton.Clicked(gtx)
+
+	if resume || newGame {
+		ui.mutex.Lock()
+		ui.onResumeChoice = nil
+		ui.mutex.Unlock()
+
+		onChoice(resume)
+		return
+	}
+
+	drawRect(gtx, 0, 0, gtx.Constraints.Max.X, gtx.Constraints.Max.Y, slightDark)
+
+	layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Min.X = 0
+		return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				label := material.Label(theme, unit.Sp(28), "Resume the saved game?")
+				label.Color = whiteColor
+				return label.Layout(gtx)
+			}),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(16)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Rigid(material.Button(theme, &ui.resumeButton, "Resume").Layout),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(16)}.Layout),
+					layout.Rigid(material.Button(theme, &ui.newGameButton, "New game").Layout),
+				)
+			}),
+		)
+	})
+}
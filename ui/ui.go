@@ -8,6 +8,7 @@ import (
 	"gioui.org/f32"
 	"gioui.org/io/event"
 	"gioui.org/io/input"
+	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/layout"
 	"gioui.org/op"
@@ -21,6 +22,7 @@ import (
 	"log"
 	"math"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -30,7 +32,7 @@ const AnimationSleepMs = 200
 
 const UseStateAsBackgroundColor = true
 
-func BuildUI(state *engine.State) *UI {
+func BuildUI(state engine.State) *UI {
 
 	ui := UI{
 		animationStep:  Idle,
@@ -42,11 +44,6 @@ func BuildUI(state *engine.State) *UI {
 		dragStart:      f32.Point{X: -1, Y: -1},
 	}
 
-	ui.Delay = func() {
-		println(fmt.Sprintf("Sleeping for %d ms", AnimationSleepMs))
-		time.Sleep(AnimationSleepMs * time.Millisecond)
-	}
-
 	ui.state = state
 
 	return &ui
@@ -62,19 +59,46 @@ type UI struct {
 	lastFrameTime      time.Time
 	clickables         []widget.Clickable
 	OnSwap             func(action engine.Action)
-	Delay              func()
-	OnSwapFinished     func()
+	OnFrame            func()
+	OnUndo             func()
+	OnRedo             func()
+	OnClose            func()
+	TimeLeft           func() time.Duration
+	Status             func(state engine.State) string
+	events             []engine.Event
+	mutex              sync.Mutex
+	pendingState       *engine.State
+	gameOver           bool
+	hint               *engine.Action
+	hintDelay          time.Duration
+	lastInteraction    time.Time
 	score              int
-	state              *engine.State
+	state              engine.State
 	mouseLocation      f32.Point
 	pressed            bool
 	dragStart          f32.Point
 	alreadySwapped     bool
+	window             *app.Window
+	onResumeChoice     func(resume bool)
+	resumeButton       widget.Clickable
+	newGameButton      widget.Clickable
 }
 
 func (ui *UI) onDragFar(gtx layout.Context) {
 	println(fmt.Sprintf("Dragged far at %f, %f", ui.dragStart.X, ui.dragStart.Y))
 
+	if ui.gameOver || (ui.TimeLeft != nil && ui.TimeLeft() == 0) {
+		println("Game over, ignoring swap")
+		return
+	}
+
+	if ui.IsBusy() {
+		println("Still animating the last turn, ignoring swap")
+		return
+	}
+
+	ui.SetHint(nil, 0)
+
 	// find the cell at the dragStart
 	cellX := int(gtx.Metric.PxToDp(int(ui.dragStart.X)) / cellSizeDp)
 	cellY := int(gtx.Metric.PxToDp(int(ui.dragStart.Y)) / cellSizeDp)
@@ -84,27 +108,109 @@ func (ui *UI) onDragFar(gtx layout.Context) {
 	dir := ui.findDragDirection()
 
 	if dir == -1 {
-		panic("Invalid direction")
+		println("Invalid direction, ignoring drag")
+		return
 	}
 
 	from := engine.Coord{X: cellX, Y: cellY}
 	dest := engine.GetNeighbor(dir, from)
 
-	ui.SetAnimStep(Swap)
-
 	// swap the 2 cells in state
 	ui.OnSwap(engine.Action{
 		From: from,
 		To:   dest,
 	})
+}
 
-	// schedule onSwapFinished for later (1s)
-	go func() {
-		if ui.Delay != nil {
-			ui.Delay()
+// Enqueue can be called from any goroutine, it receives the events of the engine, they are animated one after the other by the draw loop
+func (ui *UI) Enqueue(event engine.Event) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	ui.events = append(ui.events, event)
+}
+
+func (ui *UI) nextEvent() (engine.Event, bool) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	if len(ui.events) == 0 {
+		return nil, false
+	}
+
+	event := ui.events[0]
+	ui.events = ui.events[1:]
+
+	return event, true
+}
+
+// IsBusy tells whether a turn is still animated, or the player has not answered the resume prompt yet
+func (ui *UI) IsBusy() bool {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	return ui.animationStep != Idle || len(ui.events) > 0 || ui.onResumeChoice != nil
+}
+
+// advanceAnimation starts the animation of the next queued event once the current one is over
+func (ui *UI) advanceAnimation() {
+	for ui.animationStep == Idle || time.Since(ui.AnimationSince) >= AnimationSleepMs*time.Millisecond {
+		if ui.pendingState != nil {
+			ui.state = *ui.pendingState
+			ui.pendingState = nil
 		}
-		ui.OnSwapFinished()
-	}()
+
+		event, ok := ui.nextEvent()
+		if !ok {
+			return
+		}
+
+		ui.applyEvent(event)
+	}
+}
+
+func (ui *UI) applyEvent(event engine.Event) {
+	switch ev := event.(type) {
+	case engine.SwapCommitted:
+		ui.state = ev.State
+		ui.SetAnimStep(Swap)
+		ui.SetAnimStart()
+	case engine.MatchesExploded:
+		// keep the candies on screen until they have shrunk
+		ui.Destroyed = ev.Mask()
+		ui.pendingState = &ev.State
+		ui.SetAnimStep(Explode)
+		ui.SetAnimStart()
+	case engine.ScoreChanged:
+		ui.SetScore(ev.Score)
+	case engine.CandiesFell:
+		ui.Fallen = ev.Mask()
+		ui.state = ev.State
+		ui.SetAnimStep(Fall)
+		ui.SetAnimStart()
+	case engine.BoardRefilled:
+		ui.Filled = ev.Mask()
+		ui.state = ev.State
+		ui.SetAnimStep(Refill)
+		ui.SetAnimStart()
+	case engine.BoardShuffled:
+		ui.Filled = ev.Mask()
+		ui.state = ev.State
+		ui.SetAnimStep(Refill)
+		ui.SetAnimStart()
+	case engine.TurnSettled:
+		ui.state = ev.State
+		ui.SetAnimStep(Idle)
+		ui.SetAnimStart()
+	case engine.GameOver:
+		ui.gameOver = true
+	case engine.TurnUndone:
+		ui.state = ev.State
+		ui.gameOver = false
+		ui.SetScore(ev.State.Score)
+		ui.SetAnimStep(Idle)
+		ui.SetAnimStart()
+	}
 }
 
 func (ui *UI) findDragDirection() engine.Direction {
@@ -150,19 +256,32 @@ func (ui *UI) draw(window *app.Window) error {
 	for {
 		switch e := window.Event().(type) {
 		case app.DestroyEvent:
+			if ui.OnClose != nil {
+				ui.OnClose()
+			}
 			return e.Err
 		case app.FrameEvent:
 			gtx := app.NewContext(&ops, e)
 			// mouse handler tag
 			event.Op(&ops, tag)
 
+			if ui.OnFrame != nil {
+				ui.OnFrame()
+			}
+			ui.advanceAnimation()
+
 			// handle events and draw frame
 			ui.drawBackground(gtx)
 			ui.drawGrid(gtx)
+			ui.drawHint(gtx)
 			ui.handleEvents(e.Source, tag)
+			ui.handleKeys(e.Source)
 			ui.drawAndHandleMouse(gtx)
 			ui.drawScore(theme, gtx)
+			ui.drawTimeLeft(theme, gtx)
+			ui.drawStatus(theme, gtx)
 			ui.handleFPS(gtx, theme)
+			ui.drawResumePrompt(theme, gtx)
 
 			// send the frame to the window
 			e.Frame(gtx.Ops)
@@ -205,6 +324,33 @@ func (ui *UI) drawScore(theme *material.Theme, gtx layout.Context) layout.Dimens
 	return material.Label(theme, unit.Sp(24), fmt.Sprintf("Score: %d", ui.score)).Layout(gtx)
 }
 
+func (ui *UI) drawTimeLeft(theme *material.Theme, gtx layout.Context) {
+	var text string
+
+	switch {
+	case ui.gameOver:
+		text = "Game over!"
+	case ui.TimeLeft != nil:
+		text = fmt.Sprintf("Time: %d", int(math.Ceil(ui.TimeLeft().Seconds())))
+	default:
+		return
+	}
+
+	stack := op.Offset(image.Point{X: 0, Y: 40}).Push(gtx.Ops)
+	material.Label(theme, unit.Sp(24), text).Layout(gtx)
+	stack.Pop()
+}
+
+func (ui *UI) drawStatus(theme *material.Theme, gtx layout.Context) {
+	if ui.Status == nil {
+		return
+	}
+
+	stack := op.Offset(image.Point{X: 0, Y: 80}).Push(gtx.Ops)
+	material.Label(theme, unit.Sp(20), ui.Status(ui.state)).Layout(gtx)
+	stack.Pop()
+}
+
 func (ui *UI) drawAndHandleMouse(gtx layout.Context) {
 	// draw circle at the drag start location
 	if ui.dragStart.X != -1 && ui.dragStart.Y != -1 {
@@ -279,7 +425,39 @@ func (ui *UI) getBackgroundColor() color.NRGBA {
 	case Refill:
 		return darkPurpleColor
 	default:
-		panic(fmt.Sprintf("Invalid animation step: %d", ui.animationStep))
+		return ccBackgroundColor
+	}
+}
+
+// handleKeys undoes a turn on ctrl+z and redoes it on ctrl+y or ctrl+shift+z
+func (ui *UI) handleKeys(source input.Source) {
+	for {
+		ev, ok := source.Event(
+			key.Filter{Name: "Z", Required: key.ModShortcut, Optional: key.ModShift},
+			key.Filter{Name: "Y", Required: key.ModShortcut},
+		)
+
+		if !ok {
+			break
+		}
+
+		keyEvent, ok := ev.(key.Event)
+		if !ok || keyEvent.State != key.Press {
+			continue
+		}
+
+		if ui.IsBusy() {
+			println("Still animating the last turn, ignoring shortcut")
+			continue
+		}
+
+		redo := keyEvent.Name == "Y" || keyEvent.Modifiers.Contain(key.ModShift)
+
+		if redo && ui.OnRedo != nil {
+			ui.OnRedo()
+		} else if !redo && ui.OnUndo != nil {
+			ui.OnUndo()
+		}
 	}
 }
 
@@ -299,6 +477,7 @@ func (ui *UI) handleEvents(source input.Source, tag *bool) {
 			case pointer.Move:
 				ui.mouseLocation = pointerEvent.Position
 			case pointer.Press:
+				ui.lastInteraction = time.Now()
 				ui.pressed = true
 				ui.dragStart = pointerEvent.Position
 			case pointer.Release:
@@ -317,6 +496,12 @@ func (ui *UI) drawGrid(gtx layout.Context) {
 		for j := 0; j < ui.Width(); j++ {
 			c := engine.Coord{X: j, Y: i}
 
+			if ui.state.GetCell(c) == engine.Void {
+				continue
+			}
+
+			ui.drawJelly(gtx, c, ui.state.Board.GetJelly(c))
+
 			sizePct := ui.findCellSizeForState(c)
 			fallPct := ui.findCellFallForState(c)
 
@@ -325,6 +510,57 @@ func (ui *UI) drawGrid(gtx layout.Context) {
 	}
 }
 
+// drawJelly draws the jelly layers under a cell, more opaque with more layers
+func (ui *UI) drawJelly(gtx layout.Context, coord engine.Coord, layers int) {
+	if layers <= 0 {
+		return
+	}
+
+	cellSize := gtx.Dp(cellSizeDp)
+
+	jellyColor := slightPink
+	jellyColor.A = uint8(math.Min(255, float64(60*layers)))
+
+	rect := clip.Rect{
+		Min: image.Point{X: coord.X * cellSize, Y: coord.Y * cellSize},
+		Max: image.Point{X: (coord.X + 1) * cellSize, Y: (coord.Y + 1) * cellSize},
+	}
+
+	paint.FillShape(gtx.Ops, jellyColor, rect.Op())
+}
+
+func (ui *UI) SetHint(hint *engine.Action, delay time.Duration) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	ui.hint = hint
+	ui.hintDelay = delay
+}
+
+func (ui *UI) drawHint(gtx layout.Context) {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+
+	if ui.hint == nil || ui.animationStep != Idle {
+		return
+	}
+
+	idleSince := ui.AnimationSince
+	if ui.lastInteraction.After(idleSince) {
+		idleSince = ui.lastInteraction
+	}
+
+	if time.Since(idleSince) < ui.hintDelay {
+		return
+	}
+
+	cellSize := gtx.Dp(cellSizeDp)
+
+	for _, c := range []engine.Coord{ui.hint.From, ui.hint.To} {
+		drawCircle(c.X*cellSize+cellSize/2, c.Y*cellSize+cellSize/2, gtx, slightGreen, cellSize/3)
+	}
+}
+
 func (ui *UI) findCellFallForState(coord engine.Coord) float64 {
 	fallPct := float64(1)
 
@@ -377,7 +613,8 @@ func drawCircle(
 func (ui *UI) drawCell(cellSize unit.Dp, gtx layout.Context, coord engine.Coord, cell engine.Cell, sizePct float32, fallPct float64) {
 
 	if coord.X < 0 || coord.Y < 0 {
-		panic(fmt.Sprintf("Invalid negative cell position: %d, %d", coord.X, coord.Y))
+		println(fmt.Sprintf("Invalid negative cell position: %d, %d", coord.X, coord.Y))
+		return
 	}
 
 	clickable := &ui.clickables[coord.Y*ui.Width()+coord.X]
@@ -398,7 +635,17 @@ func (ui *UI) drawCell(cellSize unit.Dp, gtx layout.Context, coord engine.Coord,
 	// draw the square
 	clickable.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 		// draw the square
-		paint.Fill(gtx.Ops, getColor(cell))
+		fill := getColor(cell.Color())
+		if cell.HP() > 1 {
+			fill = darkGrayColor
+		}
+
+		paint.Fill(gtx.Ops, fill)
+
+		if cell.Special() == engine.TimeBonus {
+			center := int(float32(gtx.Dp(cellSizeDp)) * sizePct / 2)
+			drawCircle(center, center, gtx, whiteColor, center/3)
+		}
 
 		return layout.Dimensions{
 			Size: image.Point{
@@ -411,7 +658,8 @@ func (ui *UI) drawCell(cellSize unit.Dp, gtx layout.Context, coord engine.Coord,
 
 func drawRect(gtx layout.Context, x, y, width, height int, color color.NRGBA) {
 	if width < 0 || height < 0 {
-		panic("Invalid negative width or height")
+		println("Invalid negative width or height")
+		return
 	}
 
 	if width == 0 || height == 0 {
@@ -443,13 +691,20 @@ func getColor(cell engine.Cell) color.NRGBA {
 		return purpleColor
 	case engine.Orange:
 		return orangeColor
+	case engine.Blocker:
+		return grayColor
+	case engine.Ingredient:
+		return brownColor
+	case engine.Void:
+		return emptyColor
 	default:
-		panic("Invalid cell")
+		return maroon
 	}
 
 }
 
 func (ui *UI) run(window *app.Window) error {
+	ui.window = window
 	ui.draw(window)
 	return nil
 }
@@ -485,13 +740,13 @@ func showAnimationStep(step AnimationStep) string {
 	case Refill:
 		return "Refill"
 	default:
-		panic(fmt.Sprintf("Invalid animation step: %d", step))
+		return fmt.Sprintf("Unknown(%d)", step)
 	}
 }
 
 func RunUI(ui *UI) {
 	if ui.Width() <= 0 || ui.Height() <= 0 {
-		panic(fmt.Sprintf("Invalid board dimensions: %d, %d", ui.Width(), ui.Height()))
+		log.Fatalf("Invalid board dimensions: %d, %d", ui.Width(), ui.Height())
 	}
 
 	go func() {